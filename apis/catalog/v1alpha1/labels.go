@@ -0,0 +1,51 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+// Labels set on catalog resources, so that they can be selected by what they
+// offer.
+const (
+	// LabelManagedBy is set on every catalog resource the provider populates.
+	LabelManagedBy = "app.kubernetes.io/managed-by"
+
+	// LabelSlug is the slug of a Plan or OperatingSystem, or the code of a
+	// Metro, as used in the fields of Devices.
+	LabelSlug = "catalog.metal.equinix.com/slug"
+
+	LabelClass   = "catalog.metal.equinix.com/class"
+	LabelLine    = "catalog.metal.equinix.com/line"
+	LabelLegacy  = "catalog.metal.equinix.com/legacy"
+	LabelCountry = "catalog.metal.equinix.com/country"
+	LabelDistro  = "catalog.metal.equinix.com/distro"
+	LabelVersion = "catalog.metal.equinix.com/version"
+
+	// LabelMetroPrefix prefixes the code of each metro a Plan is offered in.
+	// The value of the label is the capacity level of the plan in the metro,
+	// or "available" if the capacity is not known.
+	LabelMetroPrefix = "metro.catalog.metal.equinix.com/"
+
+	// LabelDeploymentTypePrefix prefixes each deployment type of a Plan, such
+	// as on_demand or spot_market. The value of the label is "true".
+	LabelDeploymentTypePrefix = "deployment-type.catalog.metal.equinix.com/"
+
+	// LabelPlanPrefix prefixes the slug of each plan an OperatingSystem can
+	// be provisioned on. The value of the label is "true".
+	LabelPlanPrefix = "plan.catalog.metal.equinix.com/"
+)
+
+// ManagedByValue is the value of LabelManagedBy.
+const ManagedByValue = "provider-equinix-metal"
@@ -0,0 +1,60 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MetroObservation is the observed state of an Equinix Metal metro.
+type MetroObservation struct {
+	ID      string `json:"id"`
+	Code    string `json:"code"`
+	Name    string `json:"name,omitempty"`
+	Country string `json:"country,omitempty"`
+}
+
+// A MetroStatus reflects the observed state of a Metro.
+type MetroStatus struct {
+	AtProvider MetroObservation `json:"atProvider,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// A Metro is an observe-only resource that represents an Equinix Metal metro.
+// Metros are populated by the provider, and named by their code.
+// +kubebuilder:printcolumn:name="CODE",type="string",JSONPath=".status.atProvider.code"
+// +kubebuilder:printcolumn:name="NAME",type="string",JSONPath=".status.atProvider.name"
+// +kubebuilder:printcolumn:name="COUNTRY",type="string",JSONPath=".status.atProvider.country"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={equinix-catalog}
+type Metro struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status MetroStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// MetroList contains a list of Metros
+type MetroList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Metro `json:"items"`
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// OperatingSystemObservation is the observed state of an Equinix Metal
+// operating system.
+type OperatingSystemObservation struct {
+	Slug    string `json:"slug"`
+	Name    string `json:"name,omitempty"`
+	Distro  string `json:"distro,omitempty"`
+	Version string `json:"version,omitempty"`
+
+	// ProvisionableOn are the slugs of the plans the operating system can be
+	// provisioned on.
+	ProvisionableOn []string `json:"provisionableOn,omitempty"`
+}
+
+// An OperatingSystemStatus reflects the observed state of an OperatingSystem.
+type OperatingSystemStatus struct {
+	AtProvider OperatingSystemObservation `json:"atProvider,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// An OperatingSystem is an observe-only resource that represents an Equinix
+// Metal operating system. OperatingSystems are populated by the provider, and
+// named by their slug.
+// +kubebuilder:printcolumn:name="SLUG",type="string",JSONPath=".status.atProvider.slug"
+// +kubebuilder:printcolumn:name="DISTRO",type="string",JSONPath=".status.atProvider.distro"
+// +kubebuilder:printcolumn:name="VERSION",type="string",JSONPath=".status.atProvider.version"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={equinix-catalog},shortName=os
+type OperatingSystem struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status OperatingSystemStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// OperatingSystemList contains a list of OperatingSystems
+type OperatingSystemList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []OperatingSystem `json:"items"`
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PlanPricing is the price of a plan in US dollars, as a decimal string.
+type PlanPricing struct {
+	Hour  string `json:"hour,omitempty"`
+	Month string `json:"month,omitempty"`
+}
+
+// A PlanComponent is a number of identical CPUs or NICs.
+type PlanComponent struct {
+	Count int    `json:"count,omitempty"`
+	Type  string `json:"type,omitempty"`
+}
+
+// A PlanDrive is a number of identical drives.
+type PlanDrive struct {
+	Count int    `json:"count,omitempty"`
+	Size  string `json:"size,omitempty"`
+	Type  string `json:"type,omitempty"`
+}
+
+// PlanSpecs are the hardware specs of a plan.
+type PlanSpecs struct {
+	CPUs   []PlanComponent `json:"cpus,omitempty"`
+	Memory string          `json:"memory,omitempty"`
+	Drives []PlanDrive     `json:"drives,omitempty"`
+	NICs   []PlanComponent `json:"nics,omitempty"`
+	RAID   bool            `json:"raid,omitempty"`
+	TXT    bool            `json:"txt,omitempty"`
+}
+
+// PlanAvailability is the availability of a plan in a metro.
+type PlanAvailability struct {
+	Metro string `json:"metro"`
+
+	// Capacity is the capacity level of the plan in the metro, such as
+	// normal, limited or unavailable, if it is known.
+	// +optional
+	Capacity string `json:"capacity,omitempty"`
+}
+
+// PlanObservation is the observed state of an Equinix Metal plan.
+type PlanObservation struct {
+	ID              string             `json:"id"`
+	Slug            string             `json:"slug"`
+	Name            string             `json:"name,omitempty"`
+	Description     string             `json:"description,omitempty"`
+	Line            string             `json:"line,omitempty"`
+	Class           string             `json:"class,omitempty"`
+	Legacy          bool               `json:"legacy,omitempty"`
+	DeploymentTypes []string           `json:"deploymentTypes,omitempty"`
+	Pricing         PlanPricing        `json:"pricing,omitempty"`
+	Specs           PlanSpecs          `json:"specs,omitempty"`
+	Metros          []PlanAvailability `json:"metros,omitempty"`
+	Facilities      []string           `json:"facilities,omitempty"`
+}
+
+// A PlanStatus reflects the observed state of a Plan.
+type PlanStatus struct {
+	AtProvider PlanObservation `json:"atProvider,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// A Plan is an observe-only resource that represents an Equinix Metal plan.
+// Plans are populated by the provider, and named by their slug.
+// +kubebuilder:printcolumn:name="SLUG",type="string",JSONPath=".status.atProvider.slug"
+// +kubebuilder:printcolumn:name="LINE",type="string",JSONPath=".status.atProvider.line"
+// +kubebuilder:printcolumn:name="HOURLY",type="string",JSONPath=".status.atProvider.pricing.hour"
+// +kubebuilder:printcolumn:name="MEMORY",type="string",JSONPath=".status.atProvider.specs.memory"
+// +kubebuilder:printcolumn:name="LEGACY",type="boolean",JSONPath=".status.atProvider.legacy",priority=1
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={equinix-catalog}
+type Plan struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status PlanStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PlanList contains a list of Plans
+type PlanList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Plan `json:"items"`
+}
@@ -20,6 +20,7 @@ package apis
 import (
 	"k8s.io/apimachinery/pkg/runtime"
 
+	catalogv1alpha1 "github.com/packethost/crossplane-provider-equinix-metal/apis/catalog/v1alpha1"
 	portsv1alpha1 "github.com/packethost/crossplane-provider-equinix-metal/apis/ports/v1alpha1"
 	serverv1alpha2 "github.com/packethost/crossplane-provider-equinix-metal/apis/server/v1alpha2"
 	packetv1beta1 "github.com/packethost/crossplane-provider-equinix-metal/apis/v1beta1"
@@ -30,6 +31,7 @@ func init() {
 	// Register the types with the Scheme so the components can map objects to GroupVersionKinds and back
 	AddToSchemes = append(AddToSchemes,
 		packetv1beta1.SchemeBuilder.AddToScheme,
+		catalogv1alpha1.SchemeBuilder.AddToScheme,
 		portsv1alpha1.SchemeBuilder.AddToScheme,
 		serverv1alpha2.SchemeBuilder.AddToScheme,
 		vlanv1alpha1.SchemeBuilder.AddToScheme,
@@ -0,0 +1,151 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+)
+
+// Reasons a Device cannot be provisioned. They prefix the message of the
+// ReconcileError a Device that cannot be created reports.
+const (
+	ReasonPlanUnavailable             xpv1.ConditionReason = "PlanUnavailable"
+	ReasonOperatingSystemIncompatible xpv1.ConditionReason = "OperatingSystemIncompatible"
+	ReasonLocationUnavailable         xpv1.ConditionReason = "LocationUnavailable"
+	ReasonInsufficientCapacity        xpv1.ConditionReason = "InsufficientCapacity"
+)
+
+// TypeUserDataRendered indicates whether the templated or multipart userdata
+// of a Device could be rendered, within the size the API accepts.
+const TypeUserDataRendered xpv1.ConditionType = "UserDataRendered"
+
+// Reasons the userdata of a Device was or was not rendered.
+const (
+	ReasonUserDataRendered            xpv1.ConditionReason = "Rendered"
+	ReasonTemplateReferenceUnresolved xpv1.ConditionReason = "ReferenceUnresolved"
+	ReasonTemplateRenderFailed        xpv1.ConditionReason = "RenderFailed"
+	ReasonUserDataTooLarge            xpv1.ConditionReason = "TooLarge"
+)
+
+// UserDataRendered returns a condition that indicates the templated or
+// multipart userdata of a Device was rendered.
+func UserDataRendered() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeUserDataRendered,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonUserDataRendered,
+	}
+}
+
+// UserDataUnrendered returns a condition that indicates the userdata of a
+// Device could not be rendered for the supplied reason.
+func UserDataUnrendered(reason xpv1.ConditionReason, err error) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeUserDataRendered,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            err.Error(),
+	}
+}
+
+// TypeExpiring indicates whether a Device will soon be deleted, as its
+// termination time approaches.
+const TypeExpiring xpv1.ConditionType = "Expiring"
+
+// Reasons a Device is or is not expiring.
+const (
+	ReasonTerminationTimeApproaching xpv1.ConditionReason = "TerminationTimeApproaching"
+	ReasonTerminationTimeExtended    xpv1.ConditionReason = "TerminationTimeExtended"
+)
+
+// Expiring returns a condition that indicates a Device will be deleted at
+// the supplied termination time.
+func Expiring(t metav1.Time) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeExpiring,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonTerminationTimeApproaching,
+		Message:            "the Device will be deleted at " + t.UTC().Format(time.RFC3339),
+	}
+}
+
+// NotExpiring returns a condition that indicates a Device that was expiring
+// is no longer, as its termination time was extended.
+func NotExpiring() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeExpiring,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonTerminationTimeExtended,
+	}
+}
+
+// TypeNetworkTypeConverted indicates whether a Device was converted to the
+// network type of its spec, or the step its conversion is at.
+const TypeNetworkTypeConverted xpv1.ConditionType = "NetworkTypeConverted"
+
+// Reasons the network type of a Device was or was not converted.
+const (
+	ReasonNetworkTypeConverted        xpv1.ConditionReason = "Converted"
+	ReasonNetworkTypeConverting       xpv1.ConditionReason = "Converting"
+	ReasonNetworkTypeConversionFailed xpv1.ConditionReason = "ConversionFailed"
+)
+
+// NetworkTypeConverted returns a condition that indicates the supplied network
+// type conversion of a Device completed.
+func NetworkTypeConverted(c NetworkConversion) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeNetworkTypeConverted,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonNetworkTypeConverted,
+		Message:            fmt.Sprintf("converted network type from %s to %s", c.From, c.To),
+	}
+}
+
+// NetworkTypeConverting returns a condition that indicates the supplied
+// network type conversion of a Device is at the supplied step.
+func NetworkTypeConverting(c NetworkConversion, step int) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeNetworkTypeConverted,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonNetworkTypeConverting,
+		Message:            fmt.Sprintf("converting network type from %s to %s: step %d of %d, %s", c.From, c.To, step+1, len(c.Steps), c.Steps[step].Name),
+	}
+}
+
+// NetworkTypeConversionFailed returns a condition that indicates the supplied
+// network type conversion of a Device failed at the supplied step.
+func NetworkTypeConversionFailed(c NetworkConversion, step int, err error) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeNetworkTypeConverted,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonNetworkTypeConversionFailed,
+		Message:            fmt.Sprintf("cannot convert network type from %s to %s: step %d of %d, %s: %s", c.From, c.To, step+1, len(c.Steps), c.Steps[step].Name, err),
+	}
+}
@@ -20,6 +20,7 @@ import (
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 const (
@@ -51,6 +52,47 @@ const (
 	StateQueued = "queued"
 )
 
+// Policies for applying changes to the userdata of a created Device.
+const (
+	// UserDataUpdatePolicyUpdate updates the userdata of the Device, which
+	// takes effect when cloud-init next runs.
+	UserDataUpdatePolicyUpdate = "Update"
+
+	// UserDataUpdatePolicyReinstall updates the userdata of the Device and
+	// reinstalls its operating system, without preserving its data.
+	UserDataUpdatePolicyReinstall = "Reinstall"
+)
+
+// Steps of a network type conversion.
+const (
+	// NetworkConversionStepBondPorts bonds the ports of the Device.
+	NetworkConversionStepBondPorts = "BondPorts"
+
+	// NetworkConversionStepDisbondPorts breaks the bond of the ports of the
+	// Device.
+	NetworkConversionStepDisbondPorts = "DisbondPorts"
+
+	// NetworkConversionStepConvertPorts converts the ports of the Device to
+	// layer 2, releasing their IP addresses.
+	NetworkConversionStepConvertPorts = "ConvertPorts"
+
+	// NetworkConversionStepReassignIPs converts the bonded port of the Device
+	// to layer 3, assigning it IP addresses.
+	NetworkConversionStepReassignIPs = "ReassignIPs"
+)
+
+// Phases of a network type conversion step.
+const (
+	// NetworkConversionPhasePending indicates a step is yet to be completed.
+	NetworkConversionPhasePending = "Pending"
+
+	// NetworkConversionPhaseCompleted indicates a step was completed.
+	NetworkConversionPhaseCompleted = "Completed"
+
+	// NetworkConversionPhaseFailed indicates a step failed, and is retried.
+	NetworkConversionPhaseFailed = "Failed"
+)
+
 // TODO: make optional parameters pointers and add +optional
 
 // DeviceSpec defines the desired state of Device
@@ -76,6 +118,7 @@ type DeviceStatus struct {
 // +kubebuilder:printcolumn:name="METRO",type="string",JSONPath=".status.atProvider.metro"
 // +kubebuilder:printcolumn:name="FACILITY",type="string",JSONPath=".status.atProvider.facility",priority=1
 // +kubebuilder:printcolumn:name="IPV4",type="string",JSONPath=".status.atProvider.ipv4"
+// +kubebuilder:printcolumn:name="EXPIRES-IN",type="string",JSONPath=".status.atProvider.remainingLifetime"
 // +kubebuilder:printcolumn:name="RECLAIM-POLICY",type="string",JSONPath=".spec.reclaimPolicy"
 // +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
 // +kubebuilder:subresource:status
@@ -106,6 +149,94 @@ type IPAddress struct {
 	Reservations  []string `json:"ip_reservations,omitempty"`
 }
 
+// Storage is a custom storage layout of a Device, partitioning its disks and
+// assembling RAID arrays and filesystems from them when it is provisioned.
+type Storage struct {
+	// Disks to partition.
+	// +optional
+	Disks []Disk `json:"disks,omitempty"`
+
+	// RAID arrays to assemble from disks and partitions.
+	// +optional
+	RAID []RAID `json:"raid,omitempty"`
+
+	// Filesystems to create on disks, partitions and RAID arrays.
+	// +optional
+	Filesystems []Filesystem `json:"filesystems,omitempty"`
+}
+
+// A Disk of a Device.
+type Disk struct {
+	// Device is the path of the disk, such as /dev/sda.
+	Device string `json:"device"`
+
+	// WipeTable wipes the partition table of the disk before partitioning it.
+	// +optional
+	WipeTable bool `json:"wipeTable,omitempty"`
+
+	// Partitions to create on the disk.
+	// +optional
+	Partitions []Partition `json:"partitions,omitempty"`
+}
+
+// A Partition of a Disk.
+type Partition struct {
+	// Label of the partition, such as BIOS or ROOT.
+	Label string `json:"label"`
+
+	// Number of the partition, from 1. The partition is the device of the
+	// disk followed by its number, such as /dev/sda1.
+	// +kubebuilder:validation:Minimum=1
+	Number int `json:"number"`
+
+	// Size of the partition, such as 512MB or 4GB, or 0 for the rest of the
+	// disk.
+	Size string `json:"size"`
+}
+
+// A RAID array of disks or partitions.
+type RAID struct {
+	// Devices in the array, such as /dev/sda2.
+	Devices []string `json:"devices"`
+
+	// Level of the array.
+	// +kubebuilder:validation:Enum="0";"1";"5";"6";"10"
+	Level string `json:"level"`
+
+	// Name of the array, such as /dev/md/ROOT.
+	Name string `json:"name"`
+}
+
+// A Filesystem on a disk, partition or RAID array.
+type Filesystem struct {
+	// Mount describes the filesystem and where it is mounted.
+	Mount Mount `json:"mount"`
+}
+
+// A Mount of a Filesystem.
+type Mount struct {
+	// Device the filesystem is created on, such as /dev/sda3 or /dev/md/ROOT.
+	Device string `json:"device"`
+
+	// Format of the filesystem, such as ext4 or swap.
+	Format string `json:"format"`
+
+	// Point the filesystem is mounted at, such as /, or none for swap.
+	Point string `json:"point"`
+
+	// Create options the filesystem is created with.
+	// +optional
+	Create *FilesystemCreate `json:"create,omitempty"`
+}
+
+// FilesystemCreate are the options a Filesystem is created with.
+type FilesystemCreate struct {
+	// Options passed to the command that creates the filesystem, such as
+	// -L ROOT.
+	// +optional
+	Options []string `json:"options,omitempty"`
+}
+
 // NamespacedName represents a namespaced object name
 type NamespacedName struct {
 	Namespace string `json:"namespace"`
@@ -121,12 +252,93 @@ type DataKeySelector struct {
 	Optional bool   `json:"optional,omitempty"`
 }
 
+// A UserDataSource is a part of multipart userdata. Its content is either
+// inline, or the key of a ConfigMap or Secret.
+type UserDataSource struct {
+	// Inline content of the part.
+	// +optional
+	Inline *string `json:"inline,omitempty"`
+
+	// Ref references a ConfigMap or Secret key with the content of the part.
+	// +optional
+	Ref *DataKeySelector `json:"ref,omitempty"`
+
+	// ContentType of the part, such as text/cloud-config or
+	// text/x-shellscript. Parts without a content type are identified by
+	// cloud-init from their first line.
+	// +optional
+	ContentType string `json:"contentType,omitempty"`
+
+	// Filename of the part.
+	// +optional
+	Filename string `json:"filename,omitempty"`
+}
+
+// A UserDataTemplate renders the userdata referenced by a Device as a Go
+// template. The template is executed with the Device as .Device, the
+// referenced resources as .Resources and the values of the referenced Secrets
+// as .Secrets. Objects are accessed by the names of their JSON fields, for
+// example {{ .Device.spec.forProvider.metro }} or
+// {{ .Resources.vlan.status.atProvider.vxlan }}. The Device only has its
+// metadata.name, metadata.namespace and metadata.labels, and the projectID,
+// plan, operatingSystem and either facility or metro of spec.forProvider,
+// with the default location applied, so that it renders the same once the
+// Device is created.
+type UserDataTemplate struct {
+	// Resources are made available to the template as .Resources.<name>.
+	// +optional
+	Resources []TemplateResourceReference `json:"resources,omitempty"`
+
+	// Secrets are made available to the template as .Secrets.<name>.<key>.
+	// +optional
+	Secrets []TemplateSecretReference `json:"secrets,omitempty"`
+}
+
+// A TemplateResourceReference references a cluster scoped resource, such as
+// another managed resource, to make available to a userdata template.
+type TemplateResourceReference struct {
+	// Name the resource is available to the template as.
+	Name string `json:"name"`
+
+	// ResourceRef references the resource.
+	ResourceRef xpv1.TypedReference `json:"resourceRef"`
+}
+
+// A TemplateSecretReference references a Secret whose values are made
+// available to a userdata template.
+type TemplateSecretReference struct {
+	// Name the values of the Secret are available to the template as.
+	Name string `json:"name"`
+
+	// SecretRef references the Secret.
+	SecretRef NamespacedName `json:"secretRef"`
+}
+
 // DeviceParameters define the desired state of an Equinix Metal device.
 // https://metal.equinix.com/developers/api/#devices
 //
 // Reference values are used for optional parameters to determine if
 // LateInitialization should update the parameter after creation.
 type DeviceParameters struct {
+	// ProjectID is the ID (UUID) of the project the Device is created in. It
+	// defaults to the project of the ProviderConfig, and must be accessible
+	// with its credentials.
+	// +immutable
+	// +optional
+	ProjectID string `json:"projectID,omitempty"`
+
+	// ProjectIDRef references a VirtualNetwork to create the Device in the
+	// project of. Projects are not managed by this provider, so the ID is
+	// resolved from the observed projectID of the VirtualNetwork.
+	// +immutable
+	// +optional
+	ProjectIDRef *xpv1.Reference `json:"projectIDRef,omitempty"`
+
+	// ProjectIDSelector selects a reference to a VirtualNetwork to create the
+	// Device in the project of.
+	// +optional
+	ProjectIDSelector *xpv1.Selector `json:"projectIDSelector,omitempty"`
+
 	// +immutable
 	// +required
 	Plan string `json:"plan"`
@@ -156,12 +368,77 @@ type DeviceParameters struct {
 	// +optional
 	UserDataRef *DataKeySelector `json:"userdataRef,omitempty"`
 
+	// UserDataTemplate renders the userdata referenced by UserDataRef, or
+	// each of the UserDataSources, as a Go template when the Device is
+	// created.
+	// +optional
+	UserDataTemplate *UserDataTemplate `json:"userdataTemplate,omitempty"`
+
+	// UserDataSources are assembled, in order, into a MIME multipart
+	// cloud-init document that the Device is created with. They may not be
+	// specified together with userdata or userdataRef.
+	// +optional
+	UserDataSources []UserDataSource `json:"userdataSources,omitempty"`
+
+	// UserDataGzip compresses each of the UserDataSources. Compressed parts
+	// are typed application/x-gzip, and cloud-init types each of them from
+	// its first line once decompressed, rather than by its ContentType.
+	// +optional
+	UserDataGzip bool `json:"userdataGzip,omitempty"`
+
+	// UserDataUpdatePolicy determines how changes to the userdata of a
+	// created Device, including changes to the ConfigMaps and Secrets it is
+	// resolved from, are applied. Update, the default, updates the userdata
+	// of the Device. Reinstall also reinstalls its operating system, without
+	// preserving its data.
+	// +kubebuilder:validation:Enum=Update;Reinstall
+	// +optional
+	UserDataUpdatePolicy *string `json:"userdataUpdatePolicy,omitempty"`
+
+	// Tags of the device. Tags prefixed "crossplane.io/" are reserved; the
+	// provider adds tags naming this resource, its UID and any claim and
+	// composite resource it belongs to.
 	// +optional
 	Tags []string `json:"tags,omitempty"`
 
 	// +optional
 	Locked *bool `json:"locked,omitempty"`
 
+	// UnlockBeforeDelete unlocks a locked device when this resource is
+	// deleted, so that the device can be deleted. Deletion of a locked device
+	// is otherwise blocked until it is unlocked.
+	// +optional
+	UnlockBeforeDelete bool `json:"unlockBeforeDelete,omitempty"`
+
+	// ForceDelete deletes the device even if it is still provisioning, or
+	// has storage volumes attached.
+	// +optional
+	ForceDelete bool `json:"forceDelete,omitempty"`
+
+	// Rescue reboots the device into the rescue operating system, an
+	// in-memory Alpine Linux environment, while it is true. Setting it to
+	// false reboots the device into its own operating system. Changes to
+	// the device are not applied while it is in rescue mode.
+	// +optional
+	Rescue bool `json:"rescue,omitempty"`
+
+	// SerialOverSSH writes the endpoint, user and port of the serial over
+	// SSH (SOS) console of the device to its connection secret.
+	// +optional
+	SerialOverSSH bool `json:"serialOverSSH,omitempty"`
+
+	// TerminationTime is when the device is deleted. It is sent to the API
+	// when the device is created, and enforced by deleting this resource. It
+	// may not be specified together with TTL.
+	// +optional
+	TerminationTime *metav1.Time `json:"terminationTime,omitempty"`
+
+	// TTL is how long after this resource was created the device is deleted,
+	// for example 72h. It is sent to the API as the termination time of the
+	// device when it is created, and enforced by deleting this resource.
+	// +optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+
 	// +optional
 	IPXEScriptURL *string `json:"ipxeScriptUrl,omitempty"`
 
@@ -176,8 +453,13 @@ type DeviceParameters struct {
 	// +optional
 	HardwareReservationID *string `json:"hardwareReservationID,omitempty"`
 
+	// CustomData is arbitrary JSON data made available to the Device, such
+	// as through its metadata. It is compared semantically, so formatting and
+	// key order do not matter. A JSON document encoded as a string is also
+	// accepted.
 	// +optional
-	CustomData *string `json:"customData,omitempty"`
+	// +kubebuilder:pruning:PreserveUnknownFields
+	CustomData *runtime.RawExtension `json:"customData,omitempty"`
 
 	// +immutable
 	// +optional
@@ -201,18 +483,78 @@ type DeviceParameters struct {
 	Features map[string]string `json:"features,omitempty"`
 
 	// IPAddresses will be attached to the device. These addresses can be drawn
-	// from existing reservations.
+	// from existing reservations. Once specified, addresses are assigned and
+	// unassigned as they are added and removed, leaving the management
+	// addresses of the device assigned. Addresses added after the device is
+	// created must be drawn from reservations.
 	//
-	// +immutable
 	// +optional
 	IPAddresses []IPAddress `json:"ipAddresses,omitempty"`
+
+	// Storage is a custom storage layout of the device, partitioning its
+	// disks and assembling RAID arrays and filesystems when it is
+	// provisioned.
+	//
+	// +immutable
+	// +optional
+	Storage *Storage `json:"storage,omitempty"`
+}
+
+// A NetworkConversion records the progress of converting the network type of a
+// Device, so that a conversion that failed or was interrupted is resumed.
+type NetworkConversion struct {
+	// From is the network type of the device when the conversion started.
+	From string `json:"from"`
+
+	// To is the network type the device is being converted to.
+	To string `json:"to"`
+
+	// Steps of the conversion, in the order they are performed.
+	Steps []NetworkConversionStep `json:"steps"`
+}
+
+// Rescue mode actions.
+const (
+	RescueActionRescue = "Rescue"
+	RescueActionExit   = "Exit"
+)
+
+// A RescueAction is a requested change of the rescue mode of a device.
+type RescueAction struct {
+	// Action requested for the device: Rescue to reboot it into the rescue
+	// operating system, or Exit to reboot it into its own.
+	// +kubebuilder:validation:Enum=Rescue;Exit
+	Action string `json:"action"`
+
+	// RequestedAt is when the action was requested.
+	RequestedAt metav1.Time `json:"requestedAt"`
+}
+
+// A NetworkConversionStep is a step of a network type conversion.
+type NetworkConversionStep struct {
+	// Name of the step.
+	// +kubebuilder:validation:Enum=BondPorts;DisbondPorts;ConvertPorts;ReassignIPs
+	Name string `json:"name"`
+
+	// Phase of the step.
+	// +kubebuilder:validation:Enum=Pending;Completed;Failed
+	Phase string `json:"phase"`
+
+	// Message explains why the step failed.
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // DeviceObservation is used to reflect in the Kubernetes API, the observed
 // state of the Device resource from the Equinix Metal API.
 type DeviceObservation struct {
-	ID   string `json:"id"`
-	Href string `json:"href,omitempty"`
+	ID        string `json:"id"`
+	Href      string `json:"href,omitempty"`
+	ProjectID string `json:"projectID,omitempty"`
+
+	// ShortID is the short identifier of the device, which is the user of
+	// its serial over SSH console.
+	ShortID string `json:"shortID,omitempty"`
 
 	// Facility is where the device is deployed. This field may differ from
 	// spec.forProvider.facility when the "any" value was used.
@@ -223,6 +565,36 @@ type DeviceObservation struct {
 	IPv4                string            `json:"ipv4,omitempty"`
 	Locked              bool              `json:"locked"`
 
+	// TerminationTime is when the device is deleted, as specified by
+	// TerminationTime or TTL or as observed, whichever is earliest.
+	// +optional
+	TerminationTime *metav1.Time `json:"terminationTime,omitempty"`
+
+	// RemainingLifetime is how long until the TerminationTime.
+	// +optional
+	RemainingLifetime string `json:"remainingLifetime,omitempty"`
+
+	// UserDataHash is the SHA-256 hash of the observed userdata of the
+	// device. It is compared with the hash of the userdata resolved from the
+	// spec to detect changes to the ConfigMaps and Secrets it references.
+	// +optional
+	UserDataHash string `json:"userdataHash,omitempty"`
+
+	// NetworkConversion is the progress of converting the device to the
+	// network type of the spec. It is removed once the conversion completes.
+	// +optional
+	NetworkConversion *NetworkConversion `json:"networkConversion,omitempty"`
+
+	// Rescued is true while the device is observed running the rescue
+	// operating system.
+	// +optional
+	Rescued bool `json:"rescued,omitempty"`
+
+	// RescueAction is the rescue mode change last requested for the device.
+	// It is removed once the device is observed in the requested mode.
+	// +optional
+	RescueAction *RescueAction `json:"rescueAction,omitempty"`
+
 	// +optional
 	CreatedAt *metav1.Time `json:"createdAt,omitempty"`
 
@@ -239,8 +611,6 @@ type DeviceObservation struct {
 	// NetworkPorts []map is omitted
 	// OperatingSystem map is omitted
 	// Plan map is omitted (represented in ForProvider by Plan)
-	// Project map is omitted (represented through ProviderReference)
-	// ShortID string is omitted
 	// SSHKeys []map is omitted
 	// Volumes []map is omitted
 
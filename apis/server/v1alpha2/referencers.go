@@ -17,8 +17,14 @@ limitations under the License.
 package v1alpha2
 
 import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
 	"github.com/crossplane/crossplane-runtime/pkg/reference"
 	resource "github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	"github.com/packethost/crossplane-provider-equinix-metal/apis/vlan/v1alpha1"
 )
 
 // DeviceID extracts the ID of a Device.
@@ -31,3 +37,24 @@ func DeviceID() reference.ExtractValueFn {
 		return c.Status.AtProvider.ID
 	}
 }
+
+// ResolveReferences of this Device
+func (mg *Device) ResolveReferences(ctx context.Context, c client.Reader) error {
+	r := reference.NewAPIResolver(c, mg)
+
+	// Resolve spec.forProvider.projectID
+	rsp, err := r.Resolve(ctx, reference.ResolutionRequest{
+		CurrentValue: mg.Spec.ForProvider.ProjectID,
+		Reference:    mg.Spec.ForProvider.ProjectIDRef,
+		Selector:     mg.Spec.ForProvider.ProjectIDSelector,
+		To:           reference.To{Managed: &v1alpha1.VirtualNetwork{}, List: &v1alpha1.VirtualNetworkList{}},
+		Extract:      v1alpha1.VirtualNetworkProjectID(),
+	})
+	if err != nil {
+		return err
+	}
+	mg.Spec.ForProvider.ProjectID = rsp.ResolvedValue
+	mg.Spec.ForProvider.ProjectIDRef = rsp.ResolvedReference
+
+	return nil
+}
@@ -21,6 +21,8 @@ limitations under the License.
 package v1alpha2
 
 import (
+	"github.com/crossplane/crossplane-runtime/apis/common/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -103,6 +105,20 @@ func (in *DeviceList) DeepCopyObject() runtime.Object {
 func (in *DeviceObservation) DeepCopyInto(out *DeviceObservation) {
 	*out = *in
 	out.ProvisionPercentage = in.ProvisionPercentage.DeepCopy()
+	if in.TerminationTime != nil {
+		in, out := &in.TerminationTime, &out.TerminationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.NetworkConversion != nil {
+		in, out := &in.NetworkConversion, &out.NetworkConversion
+		*out = new(NetworkConversion)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RescueAction != nil {
+		in, out := &in.RescueAction, &out.RescueAction
+		*out = new(RescueAction)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.CreatedAt != nil {
 		in, out := &in.CreatedAt, &out.CreatedAt
 		*out = (*in).DeepCopy()
@@ -126,6 +142,16 @@ func (in *DeviceObservation) DeepCopy() *DeviceObservation {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DeviceParameters) DeepCopyInto(out *DeviceParameters) {
 	*out = *in
+	if in.ProjectIDRef != nil {
+		in, out := &in.ProjectIDRef, &out.ProjectIDRef
+		*out = new(v1.Reference)
+		**out = **in
+	}
+	if in.ProjectIDSelector != nil {
+		in, out := &in.ProjectIDSelector, &out.ProjectIDSelector
+		*out = new(v1.Selector)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Hostname != nil {
 		in, out := &in.Hostname, &out.Hostname
 		*out = new(string)
@@ -151,6 +177,23 @@ func (in *DeviceParameters) DeepCopyInto(out *DeviceParameters) {
 		*out = new(DataKeySelector)
 		**out = **in
 	}
+	if in.UserDataTemplate != nil {
+		in, out := &in.UserDataTemplate, &out.UserDataTemplate
+		*out = new(UserDataTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UserDataSources != nil {
+		in, out := &in.UserDataSources, &out.UserDataSources
+		*out = make([]UserDataSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.UserDataUpdatePolicy != nil {
+		in, out := &in.UserDataUpdatePolicy, &out.UserDataUpdatePolicy
+		*out = new(string)
+		**out = **in
+	}
 	if in.Tags != nil {
 		in, out := &in.Tags, &out.Tags
 		*out = make([]string, len(*in))
@@ -161,6 +204,15 @@ func (in *DeviceParameters) DeepCopyInto(out *DeviceParameters) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.TerminationTime != nil {
+		in, out := &in.TerminationTime, &out.TerminationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.TTL != nil {
+		in, out := &in.TTL, &out.TTL
+		*out = new(metav1.Duration)
+		**out = **in
+	}
 	if in.IPXEScriptURL != nil {
 		in, out := &in.IPXEScriptURL, &out.IPXEScriptURL
 		*out = new(string)
@@ -183,8 +235,8 @@ func (in *DeviceParameters) DeepCopyInto(out *DeviceParameters) {
 	}
 	if in.CustomData != nil {
 		in, out := &in.CustomData, &out.CustomData
-		*out = new(string)
-		**out = **in
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
 	}
 	if in.UserSSHKeys != nil {
 		in, out := &in.UserSSHKeys, &out.UserSSHKeys
@@ -215,6 +267,11 @@ func (in *DeviceParameters) DeepCopyInto(out *DeviceParameters) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Storage != nil {
+		in, out := &in.Storage, &out.Storage
+		*out = new(Storage)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceParameters.
@@ -261,6 +318,62 @@ func (in *DeviceStatus) DeepCopy() *DeviceStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Disk) DeepCopyInto(out *Disk) {
+	*out = *in
+	if in.Partitions != nil {
+		in, out := &in.Partitions, &out.Partitions
+		*out = make([]Partition, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Disk.
+func (in *Disk) DeepCopy() *Disk {
+	if in == nil {
+		return nil
+	}
+	out := new(Disk)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Filesystem) DeepCopyInto(out *Filesystem) {
+	*out = *in
+	in.Mount.DeepCopyInto(&out.Mount)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Filesystem.
+func (in *Filesystem) DeepCopy() *Filesystem {
+	if in == nil {
+		return nil
+	}
+	out := new(Filesystem)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FilesystemCreate) DeepCopyInto(out *FilesystemCreate) {
+	*out = *in
+	if in.Options != nil {
+		in, out := &in.Options, &out.Options
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FilesystemCreate.
+func (in *FilesystemCreate) DeepCopy() *FilesystemCreate {
+	if in == nil {
+		return nil
+	}
+	out := new(FilesystemCreate)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IPAddress) DeepCopyInto(out *IPAddress) {
 	*out = *in
@@ -281,6 +394,26 @@ func (in *IPAddress) DeepCopy() *IPAddress {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Mount) DeepCopyInto(out *Mount) {
+	*out = *in
+	if in.Create != nil {
+		in, out := &in.Create, &out.Create
+		*out = new(FilesystemCreate)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Mount.
+func (in *Mount) DeepCopy() *Mount {
+	if in == nil {
+		return nil
+	}
+	out := new(Mount)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NamespacedName) DeepCopyInto(out *NamespacedName) {
 	*out = *in
@@ -295,3 +428,207 @@ func (in *NamespacedName) DeepCopy() *NamespacedName {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkConversion) DeepCopyInto(out *NetworkConversion) {
+	*out = *in
+	if in.Steps != nil {
+		in, out := &in.Steps, &out.Steps
+		*out = make([]NetworkConversionStep, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkConversion.
+func (in *NetworkConversion) DeepCopy() *NetworkConversion {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkConversion)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkConversionStep) DeepCopyInto(out *NetworkConversionStep) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkConversionStep.
+func (in *NetworkConversionStep) DeepCopy() *NetworkConversionStep {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkConversionStep)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Partition) DeepCopyInto(out *Partition) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Partition.
+func (in *Partition) DeepCopy() *Partition {
+	if in == nil {
+		return nil
+	}
+	out := new(Partition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RAID) DeepCopyInto(out *RAID) {
+	*out = *in
+	if in.Devices != nil {
+		in, out := &in.Devices, &out.Devices
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RAID.
+func (in *RAID) DeepCopy() *RAID {
+	if in == nil {
+		return nil
+	}
+	out := new(RAID)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RescueAction) DeepCopyInto(out *RescueAction) {
+	*out = *in
+	in.RequestedAt.DeepCopyInto(&out.RequestedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RescueAction.
+func (in *RescueAction) DeepCopy() *RescueAction {
+	if in == nil {
+		return nil
+	}
+	out := new(RescueAction)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Storage) DeepCopyInto(out *Storage) {
+	*out = *in
+	if in.Disks != nil {
+		in, out := &in.Disks, &out.Disks
+		*out = make([]Disk, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RAID != nil {
+		in, out := &in.RAID, &out.RAID
+		*out = make([]RAID, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Filesystems != nil {
+		in, out := &in.Filesystems, &out.Filesystems
+		*out = make([]Filesystem, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Storage.
+func (in *Storage) DeepCopy() *Storage {
+	if in == nil {
+		return nil
+	}
+	out := new(Storage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TemplateResourceReference) DeepCopyInto(out *TemplateResourceReference) {
+	*out = *in
+	out.ResourceRef = in.ResourceRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateResourceReference.
+func (in *TemplateResourceReference) DeepCopy() *TemplateResourceReference {
+	if in == nil {
+		return nil
+	}
+	out := new(TemplateResourceReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TemplateSecretReference) DeepCopyInto(out *TemplateSecretReference) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateSecretReference.
+func (in *TemplateSecretReference) DeepCopy() *TemplateSecretReference {
+	if in == nil {
+		return nil
+	}
+	out := new(TemplateSecretReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UserDataSource) DeepCopyInto(out *UserDataSource) {
+	*out = *in
+	if in.Inline != nil {
+		in, out := &in.Inline, &out.Inline
+		*out = new(string)
+		**out = **in
+	}
+	if in.Ref != nil {
+		in, out := &in.Ref, &out.Ref
+		*out = new(DataKeySelector)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserDataSource.
+func (in *UserDataSource) DeepCopy() *UserDataSource {
+	if in == nil {
+		return nil
+	}
+	out := new(UserDataSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UserDataTemplate) DeepCopyInto(out *UserDataTemplate) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]TemplateResourceReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Secrets != nil {
+		in, out := &in.Secrets, &out.Secrets
+		*out = make([]TemplateSecretReference, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserDataTemplate.
+func (in *UserDataTemplate) DeepCopy() *UserDataTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(UserDataTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
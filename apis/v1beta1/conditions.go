@@ -0,0 +1,67 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+)
+
+// Reasons a ProviderConfig is or is not ready.
+const (
+	ReasonCredentialsValid    xpv1.ConditionReason = "CredentialsValid"
+	ReasonInvalidCredentials  xpv1.ConditionReason = "InvalidCredentials"
+	ReasonProjectInaccessible xpv1.ConditionReason = "ProjectInaccessible"
+)
+
+// CredentialsValid returns a condition that indicates the credentials of a
+// ProviderConfig were accepted by the Equinix Metal API, and grant access to
+// its project if it has one.
+func CredentialsValid() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               xpv1.TypeReady,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonCredentialsValid,
+	}
+}
+
+// InvalidCredentials returns a condition that indicates the credentials of a
+// ProviderConfig could not be read, or were rejected by the Equinix Metal API.
+func InvalidCredentials(err error) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               xpv1.TypeReady,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonInvalidCredentials,
+		Message:            err.Error(),
+	}
+}
+
+// ProjectInaccessible returns a condition that indicates the project of a
+// ProviderConfig does not exist or cannot be accessed with its credentials.
+func ProjectInaccessible(err error) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               xpv1.TypeReady,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonProjectInaccessible,
+		Message:            err.Error(),
+	}
+}
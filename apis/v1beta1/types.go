@@ -31,26 +31,69 @@ type ProviderConfigSpec struct {
 	// providerID).
 	// +kubebuilder:validation:Optional
 	ProjectID string `json:"projectID"`
+
+	// Metro is the default metro of Devices and VirtualNetworks that specify
+	// neither a metro nor a facility.
+	// +optional
+	Metro string `json:"metro,omitempty"`
+
+	// Facility is the default facility of Devices and VirtualNetworks that
+	// specify neither a metro nor a facility. It is ignored when Metro is
+	// specified, and overrides the facilityID of the credentials.
+	// +optional
+	Facility string `json:"facility,omitempty"`
 }
 
-// ProviderCredentials required to authenticate.
+// ProviderCredentials required to authenticate. Credentials may be a JSON object
+// with apiKey, projectID and facilityID fields, or a plain API token. The
+// Environment source reads the METAL_AUTH_TOKEN or PACKET_AUTH_TOKEN
+// environment variable when no environment variable is specified.
 type ProviderCredentials struct {
 	// Source of the provider credentials.
 	// +kubebuilder:validation:Enum=None;Secret;Environment;Filesystem
 	Source xpv1.CredentialsSource `json:"source"`
 
 	xpv1.CommonCredentialSelectors `json:",inline"`
+
+	// ProjectIDSecretRef references a Secret key that contains the Project ID
+	// (UUID), for credentials that hold only an API token. It is ignored when
+	// ProjectID is specified.
+	// +optional
+	ProjectIDSecretRef *xpv1.SecretKeySelector `json:"projectIDSecretRef,omitempty"`
 }
 
+// Token scopes of Equinix Metal API keys.
+const (
+	TokenScopeUser    = "User"
+	TokenScopeProject = "Project"
+)
+
 // A ProviderConfigStatus reflects the observed state of a ProviderConfig.
 type ProviderConfigStatus struct {
 	xpv1.ProviderConfigStatus `json:",inline"`
+
+	// Organization is the name of the organization that owns the configured
+	// project.
+	Organization string `json:"organization,omitempty"`
+
+	// ProjectName is the name of the configured project.
+	ProjectName string `json:"projectName,omitempty"`
+
+	// TokenScope is the scope of the configured API key, either User or
+	// Project.
+	TokenScope string `json:"tokenScope,omitempty"`
+
+	// ReadOnly is true if the configured API key cannot create, update or
+	// delete resources.
+	ReadOnly bool `json:"readOnly,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 
 // A ProviderConfig configures a Template provider.
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="PROJECT",type="string",JSONPath=".status.projectName"
 // +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
 // +kubebuilder:printcolumn:name="SECRET-NAME",type="string",JSONPath=".spec.credentialsSecretRef.name",priority=1
 // +kubebuilder:resource:scope=Cluster,categories={crossplane,equinix}
@@ -17,6 +17,10 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
 	"github.com/crossplane/crossplane-runtime/pkg/reference"
 	resource "github.com/crossplane/crossplane-runtime/pkg/resource"
 )
@@ -31,3 +35,35 @@ func VirtualNetworkID() reference.ExtractValueFn {
 		return c.Status.AtProvider.ID
 	}
 }
+
+// VirtualNetworkProjectID extracts the project ID of a VirtualNetwork.
+func VirtualNetworkProjectID() reference.ExtractValueFn {
+	return func(mg resource.Managed) string {
+		c, ok := mg.(*VirtualNetwork)
+		if !ok {
+			return ""
+		}
+		return c.Status.AtProvider.ProjectID
+	}
+}
+
+// ResolveReferences of this VirtualNetwork
+func (mg *VirtualNetwork) ResolveReferences(ctx context.Context, c client.Reader) error {
+	r := reference.NewAPIResolver(c, mg)
+
+	// Resolve spec.forProvider.projectID
+	rsp, err := r.Resolve(ctx, reference.ResolutionRequest{
+		CurrentValue: mg.Spec.ForProvider.ProjectID,
+		Reference:    mg.Spec.ForProvider.ProjectIDRef,
+		Selector:     mg.Spec.ForProvider.ProjectIDSelector,
+		To:           reference.To{Managed: &VirtualNetwork{}, List: &VirtualNetworkList{}},
+		Extract:      VirtualNetworkProjectID(),
+	})
+	if err != nil {
+		return err
+	}
+	mg.Spec.ForProvider.ProjectID = rsp.ResolvedValue
+	mg.Spec.ForProvider.ProjectIDRef = rsp.ResolvedReference
+
+	return nil
+}
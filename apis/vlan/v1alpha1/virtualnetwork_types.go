@@ -69,6 +69,26 @@ type VirtualNetworkList struct {
 // Reference values are used for optional parameters to determine if
 // LateInitialization should update the parameter after creation.
 type VirtualNetworkParameters struct {
+	// ProjectID is the ID (UUID) of the project the VirtualNetwork is created
+	// in. It defaults to the project of the ProviderConfig, and must be
+	// accessible with its credentials.
+	// +immutable
+	// +optional
+	ProjectID string `json:"projectID,omitempty"`
+
+	// ProjectIDRef references another VirtualNetwork to create the
+	// VirtualNetwork in the project of. Projects are not managed by this
+	// provider, so the ID is resolved from the observed projectID of the
+	// referenced VirtualNetwork.
+	// +immutable
+	// +optional
+	ProjectIDRef *xpv1.Reference `json:"projectIDRef,omitempty"`
+
+	// ProjectIDSelector selects a reference to another VirtualNetwork to
+	// create the VirtualNetwork in the project of.
+	// +optional
+	ProjectIDSelector *xpv1.Selector `json:"projectIDSelector,omitempty"`
+
 	// +immutable
 	// +optional
 	Facility string `json:"facility,omitempty"`
@@ -90,7 +110,9 @@ type VirtualNetworkParameters struct {
 type VirtualNetworkObservation struct {
 	ID           string       `json:"id"`
 	Href         string       `json:"href,omitempty"`
+	ProjectID    string       `json:"projectID,omitempty"`
 	VXLAN        int          `json:"vxlan,omitempty"`
 	FacilityCode string       `json:"facilityCode,omitempty"`
+	Metro        string       `json:"metro,omitempty"`
 	CreatedAt    *metav1.Time `json:"createdAt,omitempty"`
 }
@@ -17,8 +17,10 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"gopkg.in/alecthomas/kingpin.v2"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -27,7 +29,10 @@ import (
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 
 	"github.com/packethost/crossplane-provider-equinix-metal/apis"
+	"github.com/packethost/crossplane-provider-equinix-metal/pkg/clients"
 	"github.com/packethost/crossplane-provider-equinix-metal/pkg/controller"
+	"github.com/packethost/crossplane-provider-equinix-metal/pkg/tracing"
+	"github.com/packethost/crossplane-provider-equinix-metal/pkg/webhook"
 )
 
 func main() {
@@ -35,6 +40,17 @@ func main() {
 		app        = kingpin.New(filepath.Base(os.Args[0]), "Equinix Metal support for Crossplane.").DefaultEnvars()
 		debug      = app.Flag("debug", "Run with debug logging.").Short('d').Bool()
 		syncPeriod = app.Flag("sync", "Controller manager sync period such as 300ms, 1.5h, or 2h45m").Short('s').Default("1h").Duration()
+		apiRPS     = app.Flag("api-rps", "Average Equinix Metal API requests per second allowed per API token.").Default(strconv.Itoa(clients.DefaultRequestsPerSecond)).Float64()
+		apiBurst   = app.Flag("api-burst", "Equinix Metal API requests per API token allowed to exceed api-rps at once.").Default("0").Int()
+		snapshot   = app.Flag("project-snapshot-interval", "Interval at which all Devices and VirtualNetworks of each project are listed to serve observations, such as 1m. Resources are read individually when zero.").Default("0").Duration()
+		apiRetries = app.Flag("api-max-retries", "Maximum retries of throttled or transiently failed Equinix Metal API requests.").Default(strconv.Itoa(clients.DefaultMaxRetries)).Int()
+		otlp       = app.Flag("otlp-endpoint", "host:port of an OTLP gRPC collector to export traces of reconciles and Equinix Metal API requests to. Tracing is disabled when empty.").Default("").String()
+		otlpPlain  = app.Flag("otlp-insecure", "Connect to the OTLP collector without TLS.").Bool()
+		sampling   = app.Flag("trace-sample-ratio", "Fraction of reconciles that are traced when tracing is enabled.").Default("1").Float64()
+		catalogPC  = app.Flag("catalog-provider-config", "Name of the ProviderConfig whose credentials are used to populate the Metro, Plan and OperatingSystem resources.").Default("default").String()
+		catalogInt = app.Flag("catalog-sync-interval", "Interval at which the Metro, Plan and OperatingSystem resources are populated, such as 1h. They are not populated when zero.").Default("1h").Duration()
+		certDir    = app.Flag("webhook-tls-cert-dir", "Directory containing the tls.crt and tls.key of the validating webhook server. Webhooks are disabled when empty.").Envar("WEBHOOK_TLS_CERT_DIR").String()
+		hookPort   = app.Flag("webhook-port", "Port the validating webhook server listens on.").Default("9443").Int()
 	)
 	kingpin.MustParse(app.Parse(os.Args[1:]))
 
@@ -49,13 +65,37 @@ func main() {
 
 	log.Debug("Starting", "sync-period", syncPeriod.String())
 
+	clients.ConfigureTransport(clients.TransportOptions{
+		RequestsPerSecond: *apiRPS,
+		Burst:             *apiBurst,
+		MaxRetries:        *apiRetries,
+	})
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Endpoint:    *otlp,
+		Insecure:    *otlpPlain,
+		SampleRatio: *sampling,
+	})
+	kingpin.FatalIfError(err, "Cannot setup tracing")
+
 	cfg, err := ctrl.GetConfig()
 	kingpin.FatalIfError(err, "Cannot get API server rest config")
 
-	mgr, err := ctrl.NewManager(cfg, ctrl.Options{SyncPeriod: syncPeriod})
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{SyncPeriod: syncPeriod, Port: *hookPort, CertDir: *certDir})
 	kingpin.FatalIfError(err, "Cannot create controller manager")
 
 	kingpin.FatalIfError(apis.AddToScheme(mgr.GetScheme()), "Cannot add GCP APIs to scheme")
-	kingpin.FatalIfError(controller.Setup(mgr, log), "Cannot setup GCP controllers")
-	kingpin.FatalIfError(mgr.Start(ctrl.SetupSignalHandler()), "Cannot start controller manager")
+	kingpin.FatalIfError(controller.Setup(mgr, log, controller.Options{
+		SnapshotInterval:      *snapshot,
+		CatalogProviderConfig: *catalogPC,
+		CatalogSyncInterval:   *catalogInt,
+	}), "Cannot setup GCP controllers")
+	if *certDir != "" {
+		kingpin.FatalIfError(webhook.Setup(mgr), "Cannot setup webhooks")
+	}
+	err = mgr.Start(ctrl.SetupSignalHandler())
+	if serr := shutdownTracing(context.Background()); serr != nil {
+		log.Info("Cannot flush traces", "error", serr)
+	}
+	kingpin.FatalIfError(err, "Cannot start controller manager")
 }
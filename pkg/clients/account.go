@@ -0,0 +1,152 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clients
+
+import (
+	"net/http"
+	"path"
+
+	"github.com/packethost/packngo"
+	"github.com/pkg/errors"
+
+	"github.com/packethost/crossplane-provider-equinix-metal/apis/v1beta1"
+)
+
+const (
+	errProjectFmt      = "project %s is not accessible with the API key of the ProviderConfig"
+	errListUserKeys    = "cannot list user API keys"
+	errListProjectKeys = "cannot list project API keys"
+)
+
+// ProjectClient implements the Equinix Metal API methods needed to read
+// Projects.
+type ProjectClient interface {
+	Get(projectID string, getOpt *packngo.GetOptions) (*packngo.Project, *packngo.Response, error)
+}
+
+// build-time test that the interface is implemented
+var _ ProjectClient = (&packngo.Client{}).Projects
+
+// ValidateProject returns an error unless the supplied project exists and is
+// accessible using the supplied client.
+func ValidateProject(c ProjectClient, projectID string) error {
+	_, _, err := c.Get(projectID, nil)
+	if err != nil && isDenied(err) {
+		return errors.Wrapf(err, errProjectFmt, projectID)
+	}
+	return err
+}
+
+// ProjectID returns the ID of the supplied project, which may only be
+// described by its href when it is a field of another resource.
+func ProjectID(p *packngo.Project) string {
+	switch {
+	case p == nil:
+		return ""
+	case p.ID != "":
+		return p.ID
+	case p.URL != "":
+		return path.Base(p.URL)
+	}
+	return ""
+}
+
+// An Account describes what the API key of a Client grants access to.
+type Account struct {
+	// Organization is the name of the organization owning the project.
+	Organization string
+
+	// ProjectName is the name of the project.
+	ProjectName string
+
+	// TokenScope is v1beta1.TokenScopeUser or v1beta1.TokenScopeProject, or
+	// empty if the API key could not be found.
+	TokenScope string
+
+	// ReadOnly is true if the API key cannot make changes.
+	ReadOnly bool
+}
+
+// GetAccount confirms that the Client's API key is accepted by the Equinix
+// Metal API and grants access to the Client's project, and describes that
+// access. Only the API key is confirmed if the Client has no project, in which
+// case the organization and project name are left empty. Errors returned while
+// reading the user or project are returned unwrapped, so that callers may tell
+// an invalid API key from an inaccessible project. The scope of the API key is
+// left unknown if its API keys cannot be listed.
+func (c *Client) GetAccount() (*Account, error) {
+	a := &Account{}
+	projectID := c.GetProjectID(CredentialProjectID)
+	if projectID == "" {
+		if _, _, err := c.Client.Users.Current(); err != nil {
+			return nil, err
+		}
+	} else {
+		p, _, err := c.Client.Projects.Get(projectID, &packngo.GetOptions{Includes: []string{"organization"}})
+		if err != nil {
+			return nil, err
+		}
+		a.Organization, a.ProjectName = p.Organization.Name, p.Name
+	}
+
+	// Project API keys are not permitted to list user API keys, so the
+	// scope of an API key is that of the list it is found in.
+	keys, _, err := c.Client.APIKeys.UserList(nil)
+	if err != nil && !isDenied(err) && !IsPermanent(err) {
+		return nil, errors.Wrap(err, errListUserKeys)
+	}
+	if k := findKey(keys, c.GetAPIKey(CredentialAPIKey)); k != nil {
+		a.TokenScope, a.ReadOnly = v1beta1.TokenScopeUser, k.ReadOnly
+		return a, nil
+	}
+	if projectID == "" {
+		return a, nil
+	}
+
+	// Listing project API keys may be forbidden to read-only and project API
+	// keys. The scope is left unknown in that case.
+	keys, _, err = c.Client.APIKeys.ProjectList(projectID, nil)
+	if err != nil && !isDenied(err) && !IsPermanent(err) {
+		return nil, errors.Wrap(err, errListProjectKeys)
+	}
+	if k := findKey(keys, c.GetAPIKey(CredentialAPIKey)); k != nil {
+		a.TokenScope, a.ReadOnly = v1beta1.TokenScopeProject, k.ReadOnly
+	}
+	return a, nil
+}
+
+func findKey(keys []packngo.APIKey, token string) *packngo.APIKey {
+	for i := range keys {
+		if keys[i].Token == token {
+			return &keys[i]
+		}
+	}
+	return nil
+}
+
+// IsUnauthorized returns true if the Equinix Metal API rejected the API key
+// used to make the request that returned the supplied error.
+func IsUnauthorized(err error) bool {
+	return statusCodeOf(err) == http.StatusUnauthorized
+}
+
+// isDenied returns true if the API key used to make the request that returned
+// the supplied error is not permitted to make it.
+func isDenied(err error) bool {
+	code := statusCodeOf(err)
+	return code == http.StatusUnauthorized || code == http.StatusForbidden || code == http.StatusNotFound
+}
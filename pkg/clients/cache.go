@@ -0,0 +1,271 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clients
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/packethost/crossplane-provider-equinix-metal/apis/v1beta1"
+)
+
+const (
+	errGetProviderConfig    = "cannot get ProviderConfig"
+	errGetCredentials       = "cannot get credentials"
+	errGetCredentialsSecret = "cannot get credentials secret"
+	errGetProjectIDSecret   = "cannot get project ID secret"
+	errNoSecretRef          = "cannot extract from secret key when none specified"
+	errNoEnvToken           = "neither " + EnvAuthToken + " nor " + EnvPacketAuthToken + " environment variable is set"
+	errSecretKeyFmt         = "secret %s/%s has no key %q"
+)
+
+// The environment variables read by the Environment credentials source when
+// no environment variable is specified, in order of preference.
+const (
+	EnvAuthToken       = "METAL_AUTH_TOKEN"
+	EnvPacketAuthToken = "PACKET_AUTH_TOKEN"
+)
+
+// A ClientCache caches Equinix Metal API clients by ProviderConfig. A cached
+// client is reused until the spec of the ProviderConfig or its credentials
+// change, so that reconciles of every managed resource using the same
+// ProviderConfig share one client and its HTTP connections.
+type ClientCache struct {
+	snapshots *Snapshotter
+	catalog   *Catalog
+
+	mu      sync.Mutex
+	clients map[string]cachedClient
+}
+
+type cachedClient struct {
+	version string
+	client  *Client
+}
+
+// A ClientCacheOption configures a ClientCache.
+type ClientCacheOption func(*ClientCache)
+
+// WithSnapshotter configures the Snapshotter used to serve project snapshots
+// for the clients in the cache.
+func WithSnapshotter(s *Snapshotter) ClientCacheOption {
+	return func(c *ClientCache) {
+		c.snapshots = s
+	}
+}
+
+// WithCatalog configures the Catalog served to the clients in the cache.
+func WithCatalog(cat *Catalog) ClientCacheOption {
+	return func(c *ClientCache) {
+		c.catalog = cat
+	}
+}
+
+// NewClientCache returns an empty ClientCache.
+func NewClientCache(o ...ClientCacheOption) *ClientCache {
+	c := &ClientCache{clients: map[string]cachedClient{}}
+	for _, opt := range o {
+		opt(c)
+	}
+	return c
+}
+
+// GetClient returns an Equinix Metal API client configured by the
+// ProviderConfig referenced by the supplied managed resource. A nil ClientCache
+// returns a new client on every call.
+func (c *ClientCache) GetClient(ctx context.Context, kube client.Client, mg resource.Managed) (*Client, error) {
+	switch {
+	case mg.GetProviderConfigReference() != nil:
+	case mg.GetProviderReference() != nil:
+		return nil, errors.New("providerRef is not supported (use providerConfigRef)")
+	default:
+		return nil, errors.New("no providerConfigRef given")
+	}
+
+	pc := &v1beta1.ProviderConfig{}
+	if err := kube.Get(ctx, types.NamespacedName{Name: mg.GetProviderConfigReference().Name}, pc); err != nil {
+		return nil, errors.Wrap(err, errGetProviderConfig)
+	}
+	return c.ClientFor(ctx, kube, pc)
+}
+
+// ClientFor returns an Equinix Metal API client configured by the supplied
+// ProviderConfig. The same client is returned until the spec of the
+// ProviderConfig or its credentials change. A nil ClientCache returns a new
+// client on every call.
+func (c *ClientCache) ClientFor(ctx context.Context, kube client.Client, pc *v1beta1.ProviderConfig) (*Client, error) {
+	cl, _, err := c.VersionedClientFor(ctx, kube, pc)
+	return cl, err
+}
+
+// VersionedClientFor returns the client returned by ClientFor, and the version
+// of the ProviderConfig and credentials it was configured by. The version
+// changes whenever ClientFor returns a new client.
+func (c *ClientCache) VersionedClientFor(ctx context.Context, kube client.Client, pc *v1beta1.ProviderConfig) (*Client, string, error) {
+	name := pc.GetName()
+	config, version, err := loadCredentials(ctx, kube, pc)
+	if err != nil {
+		return nil, "", errors.Wrap(err, errGetCredentials)
+	}
+
+	if cc, ok := c.get(name); ok && cc.version == version {
+		return cc.client, version, nil
+	}
+
+	cl, err := NewClient(ctx, config)
+	if err != nil {
+		return nil, "", err
+	}
+
+	c.set(name, cachedClient{version: version, client: cl})
+	return cl, version, nil
+}
+
+// Invalidate removes any client cached for the named ProviderConfig.
+func (c *ClientCache) Invalidate(name string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.clients, name)
+}
+
+// Snapshot returns the snapshot of the supplied project, listed using the
+// supplied client. It returns nil, which contains no resources, unless the
+// cache was configured with a Snapshotter.
+func (c *ClientCache) Snapshot(cl *Client, projectID string) *ProjectSnapshot {
+	if c == nil {
+		return nil
+	}
+	return c.snapshots.Project(cl, projectID)
+}
+
+// Catalog returns a CatalogClient that serves the Catalog of the cache,
+// listed using the supplied client. A nil ClientCache, or one configured
+// without a Catalog, lists the catalog on every lookup.
+func (c *ClientCache) Catalog(cl *Client) CatalogClient {
+	if c == nil {
+		return (*Catalog)(nil).For(NewCatalogAPI(cl))
+	}
+	return c.catalog.For(NewCatalogAPI(cl))
+}
+
+func (c *ClientCache) get(name string) (cachedClient, bool) {
+	if c == nil {
+		return cachedClient{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cc, ok := c.clients[name]
+	return cc, ok
+}
+
+func (c *ClientCache) set(name string, cc cachedClient) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clients[name] = cc
+}
+
+// loadCredentials returns the credentials configured by the supplied
+// ProviderConfig, and a version that changes whenever they or the spec of the
+// ProviderConfig change. Status updates do not change the version.
+func loadCredentials(ctx context.Context, kube client.Client, pc *v1beta1.ProviderConfig) (*Credentials, string, error) {
+	cs := pc.Spec.Credentials
+	data, err := credentialData(ctx, kube, cs)
+	if err != nil {
+		return nil, "", err
+	}
+
+	projectID := pc.Spec.ProjectID
+	if projectID == "" && cs.ProjectIDSecretRef != nil {
+		p, err := secretKey(ctx, kube, *cs.ProjectIDSecretRef)
+		if err != nil {
+			return nil, "", errors.Wrap(err, errGetProjectIDSecret)
+		}
+		projectID = strings.TrimSpace(string(p))
+	}
+
+	config, err := ParseCredentials(data)
+	if err != nil {
+		return nil, "", err
+	}
+	if projectID != "" {
+		config.SetProjectID(projectID)
+	}
+	switch {
+	case pc.Spec.Metro != "":
+		config.SetMetro(pc.Spec.Metro)
+		config.SetFacilityID("")
+	case pc.Spec.Facility != "":
+		config.SetFacilityID(pc.Spec.Facility)
+	}
+
+	h := sha256.New()
+	_, _ = h.Write(data)
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(projectID))
+	return config, strconv.FormatInt(pc.GetGeneration(), 10) + "/" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// credentialData returns the credentials read from the supplied source.
+func credentialData(ctx context.Context, kube client.Client, cs v1beta1.ProviderCredentials) ([]byte, error) {
+	switch {
+	case cs.Source == xpv1.CredentialsSourceSecret:
+		if cs.SecretRef == nil {
+			return nil, errors.New(errNoSecretRef)
+		}
+		data, err := secretKey(ctx, kube, *cs.SecretRef)
+		return data, errors.Wrap(err, errGetCredentialsSecret)
+	case cs.Source == xpv1.CredentialsSourceEnvironment && cs.Env == nil:
+		for _, env := range []string{EnvAuthToken, EnvPacketAuthToken} {
+			if v := os.Getenv(env); v != "" {
+				return []byte(v), nil
+			}
+		}
+		return nil, errors.New(errNoEnvToken)
+	}
+	return resource.CommonCredentialExtractor(ctx, cs.Source, kube, cs.CommonCredentialSelectors)
+}
+
+// secretKey returns the value of the supplied Secret key.
+func secretKey(ctx context.Context, kube client.Client, sel xpv1.SecretKeySelector) ([]byte, error) {
+	s := &corev1.Secret{}
+	if err := kube.Get(ctx, types.NamespacedName{Namespace: sel.Namespace, Name: sel.Name}, s); err != nil {
+		return nil, err
+	}
+	v, ok := s.Data[sel.Key]
+	if !ok {
+		return nil, errors.Errorf(errSecretKeyFmt, sel.Namespace, sel.Name, sel.Key)
+	}
+	return v, nil
+}
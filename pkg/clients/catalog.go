@@ -0,0 +1,284 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clients
+
+import (
+	"sync"
+	"time"
+
+	"github.com/packethost/packngo"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultCatalogTTL is how long listed plans, operating systems and
+	// metros are served before they are listed again.
+	DefaultCatalogTTL = time.Hour
+
+	// DefaultCapacityTTL is how long listed capacity is served before it is
+	// listed again.
+	DefaultCapacityTTL = 5 * time.Minute
+
+	// CapacityUnavailable is the capacity level of a plan that cannot be
+	// provisioned in a location.
+	CapacityUnavailable = "unavailable"
+
+	errListPlans            = "cannot list plans"
+	errListOperatingSystems = "cannot list operating systems"
+	errListMetros           = "cannot list metros"
+	errListCapacity         = "cannot list capacity"
+)
+
+// A CatalogClient looks up the plans, operating systems, metros and capacity
+// offered by Equinix Metal. Lookups of things that are not offered return nil
+// without error.
+type CatalogClient interface {
+	Plan(slug string) (*packngo.Plan, error)
+	OperatingSystem(slug string) (*packngo.OS, error)
+	Metro(code string) (*packngo.Metro, error)
+
+	// CapacityLevel returns the capacity level of the supplied plan in the
+	// supplied metro, or in the supplied facility if no metro is supplied.
+	// It returns an empty level if the capacity is not known.
+	CapacityLevel(plan, facility, metro string) (string, error)
+}
+
+// A CatalogAPI is the part of the Equinix Metal API the catalog is listed
+// from.
+type CatalogAPI struct {
+	Plans            packngo.PlanService
+	OperatingSystems packngo.OSService
+	Metros           packngo.MetroService
+	Capacity         packngo.CapacityService
+}
+
+// NewCatalogAPI returns the CatalogAPI of the supplied client.
+func NewCatalogAPI(c *Client) CatalogAPI {
+	return CatalogAPI{
+		Plans:            c.Client.Plans,
+		OperatingSystems: c.Client.OperatingSystems,
+		Metros:           c.Client.Metros,
+		Capacity:         c.Client.CapacityService,
+	}
+}
+
+// A Catalog caches the plans, operating systems, metros and capacity offered
+// by Equinix Metal. The catalog is the same for every API token, so one Catalog
+// is shared by the clients of every ProviderConfig. It is listed using the
+// client that first needs it after it expires. The lock of the catalog is not
+// held while it is listed, so that a slow list does not block lookups of the
+// parts of the catalog that have not expired; the new list replaces the old
+// one once it is complete. Lookups of a part that is being listed wait for
+// that list rather than listing it again.
+type Catalog struct {
+	ttl         time.Duration
+	capacityTTL time.Duration
+
+	// Each part of the catalog is listed while holding its refresh lock.
+	plansRefresh            sync.Mutex
+	osRefresh               sync.Mutex
+	metrosRefresh           sync.Mutex
+	metroCapacityRefresh    sync.Mutex
+	facilityCapacityRefresh sync.Mutex
+
+	mu               sync.Mutex
+	plans            map[string]packngo.Plan
+	plansAt          time.Time
+	os               map[string]packngo.OS
+	osAt             time.Time
+	metros           map[string]packngo.Metro
+	metrosAt         time.Time
+	metroCapacity    packngo.CapacityReport
+	metroCapacityAt  time.Time
+	facilityCapacity packngo.CapacityReport
+	facilityCapAt    time.Time
+}
+
+// NewCatalog returns an empty Catalog that serves plans, operating systems
+// and metros for the supplied TTL, and capacity for the supplied capacity TTL.
+func NewCatalog(ttl, capacityTTL time.Duration) *Catalog {
+	return &Catalog{ttl: ttl, capacityTTL: capacityTTL}
+}
+
+// For returns a CatalogClient that serves the Catalog, listing it using the
+// supplied API when it has expired. A nil Catalog lists the API on every
+// lookup.
+func (c *Catalog) For(api CatalogAPI) CatalogClient {
+	if c == nil {
+		c = NewCatalog(0, 0)
+	}
+	return &catalogClient{catalog: c, api: api}
+}
+
+type catalogClient struct {
+	catalog *Catalog
+	api     CatalogAPI
+}
+
+func (c *catalogClient) Plan(slug string) (*packngo.Plan, error) {
+	cat := c.catalog
+	err := cat.refresh(&cat.plansRefresh, &cat.plansAt, cat.ttl, func() error {
+		plans, _, err := c.api.Plans.List(&packngo.ListOptions{Includes: []string{"available_in", "available_in_metros"}})
+		if err != nil {
+			return errors.Wrap(err, errListPlans)
+		}
+		m := make(map[string]packngo.Plan, len(plans))
+		for _, p := range plans {
+			m[p.Slug] = p
+		}
+		cat.mu.Lock()
+		cat.plans, cat.plansAt = m, time.Now()
+		cat.mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cat.mu.Lock()
+	defer cat.mu.Unlock()
+	p, ok := cat.plans[slug]
+	if !ok {
+		return nil, nil
+	}
+	return &p, nil
+}
+
+func (c *catalogClient) OperatingSystem(slug string) (*packngo.OS, error) {
+	cat := c.catalog
+	err := cat.refresh(&cat.osRefresh, &cat.osAt, cat.ttl, func() error {
+		oss, _, err := c.api.OperatingSystems.List()
+		if err != nil {
+			return errors.Wrap(err, errListOperatingSystems)
+		}
+		m := make(map[string]packngo.OS, len(oss))
+		for _, o := range oss {
+			m[o.Slug] = o
+		}
+		cat.mu.Lock()
+		cat.os, cat.osAt = m, time.Now()
+		cat.mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cat.mu.Lock()
+	defer cat.mu.Unlock()
+	o, ok := cat.os[slug]
+	if !ok {
+		return nil, nil
+	}
+	return &o, nil
+}
+
+func (c *catalogClient) Metro(code string) (*packngo.Metro, error) {
+	cat := c.catalog
+	err := cat.refresh(&cat.metrosRefresh, &cat.metrosAt, cat.ttl, func() error {
+		metros, _, err := c.api.Metros.List(nil)
+		if err != nil {
+			return errors.Wrap(err, errListMetros)
+		}
+		m := make(map[string]packngo.Metro, len(metros))
+		for _, mt := range metros {
+			m[mt.Code] = mt
+		}
+		cat.mu.Lock()
+		cat.metros, cat.metrosAt = m, time.Now()
+		cat.mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cat.mu.Lock()
+	defer cat.mu.Unlock()
+	m, ok := cat.metros[code]
+	if !ok {
+		return nil, nil
+	}
+	return &m, nil
+}
+
+func (c *catalogClient) CapacityLevel(plan, facility, metro string) (string, error) {
+	cat := c.catalog
+	if metro != "" {
+		err := cat.refresh(&cat.metroCapacityRefresh, &cat.metroCapacityAt, cat.capacityTTL, func() error {
+			r, _, err := c.api.Capacity.ListMetros()
+			if err != nil {
+				return errors.Wrap(err, errListCapacity)
+			}
+			cat.mu.Lock()
+			cat.metroCapacity, cat.metroCapacityAt = *r, time.Now()
+			cat.mu.Unlock()
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+		cat.mu.Lock()
+		defer cat.mu.Unlock()
+		return cat.metroCapacity[metro][plan].Level, nil
+	}
+
+	err := cat.refresh(&cat.facilityCapacityRefresh, &cat.facilityCapAt, cat.capacityTTL, func() error {
+		r, _, err := c.api.Capacity.List()
+		if err != nil {
+			return errors.Wrap(err, errListCapacity)
+		}
+		cat.mu.Lock()
+		cat.facilityCapacity, cat.facilityCapAt = *r, time.Now()
+		cat.mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	cat.mu.Lock()
+	defer cat.mu.Unlock()
+	return cat.facilityCapacity[facility][plan].Level, nil
+}
+
+// refresh lists a part of the catalog with the supplied function if the list
+// made at the supplied time has expired, while holding the supplied refresh
+// lock of the part. Lookups that wait for the lock find the part listed, and
+// do not list it again.
+func (c *Catalog) refresh(lock *sync.Mutex, at *time.Time, ttl time.Duration, list func() error) error {
+	if !c.expired(at, ttl) {
+		return nil
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if !c.expired(at, ttl) {
+		return nil
+	}
+	return list()
+}
+
+// expired returns true if the part of the catalog listed at the supplied time
+// has expired.
+func (c *Catalog) expired(at *time.Time, ttl time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return expired(*at, ttl)
+}
+
+func expired(at time.Time, ttl time.Duration) bool {
+	return at.IsZero() || time.Since(at) >= ttl
+}
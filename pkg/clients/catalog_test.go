@@ -0,0 +1,65 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clients
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/packethost/packngo"
+)
+
+// slowPlans is a PlanService whose List blocks until it is released.
+type slowPlans struct {
+	packngo.PlanService
+	calls   int32
+	release chan struct{}
+}
+
+func (p *slowPlans) List(*packngo.ListOptions) ([]packngo.Plan, *packngo.Response, error) {
+	atomic.AddInt32(&p.calls, 1)
+	<-p.release
+	return []packngo.Plan{{Slug: "c3.small.x86"}}, nil, nil
+}
+
+func TestCatalogListsOnce(t *testing.T) {
+	plans := &slowPlans{release: make(chan struct{})}
+	c := NewCatalog(time.Hour, time.Hour).For(CatalogAPI{Plans: plans})
+
+	const lookups = 8
+	var wg sync.WaitGroup
+	wg.Add(lookups)
+	for i := 0; i < lookups; i++ {
+		go func() {
+			defer wg.Done()
+			if p, err := c.Plan("c3.small.x86"); err != nil || p == nil {
+				t.Errorf("c.Plan(...): want plan, got %v, %v", p, err)
+			}
+		}()
+	}
+
+	// Give the lookups time to wait for the list before releasing it.
+	time.Sleep(50 * time.Millisecond)
+	close(plans.release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&plans.calls); got != 1 {
+		t.Errorf("Plans.List(...): want 1 call, got %d", got)
+	}
+}
@@ -22,6 +22,7 @@ type Credentials struct {
 	APIKey     string `json:"apiKey"`
 	ProjectID  string `json:"projectID"`
 	FacilityID string `json:"facilityID"`
+	Metro      string `json:"metro,omitempty"`
 }
 
 // Using these constants causes Credential methods to return the credential
@@ -30,18 +31,21 @@ const (
 	CredentialAPIKey     = ""
 	CredentialProjectID  = ""
 	CredentialFacilityID = ""
+	CredentialMetro      = ""
 )
 
 // DefaultGetter provides setters for common Equinix Metal client properties
 type DefaultGetter interface {
 	GetProjectID(string) string
 	GetFacilityID(string) string
+	GetMetro(string) string
 }
 
 // DefaultSetter provides setters for common Equinix Metal client properties
 type DefaultSetter interface {
 	SetProjectID(string)
 	SetFacilityID(string)
+	SetMetro(string)
 }
 
 // Defaulter provides getter and setters for common Equinix Metal client properties
@@ -68,6 +72,28 @@ func (c *Credentials) GetFacilityID(facilityID string) string {
 	return c.FacilityID
 }
 
+// GetMetro returns the supplied Metro or the Metro included with the Client
+// credentials (if any)
+func (c *Credentials) GetMetro(metro string) string {
+	if metro != "" {
+		return metro
+	}
+	return c.Metro
+}
+
+// DefaultLocation returns the supplied facility and metro, or the default
+// location of the Client credentials if neither is supplied. A default metro
+// takes precedence over a default facility, as the API accepts only one.
+func DefaultLocation(g DefaultGetter, facility, metro string) (string, string) {
+	if facility != "" || metro != "" {
+		return facility, metro
+	}
+	if m := g.GetMetro(CredentialMetro); m != "" {
+		return "", m
+	}
+	return g.GetFacilityID(CredentialFacilityID), ""
+}
+
 // GetAPIKey returns the supplied APIKey or the APIKey included with the
 // Client credentials (if any)
 func (c *Credentials) GetAPIKey(apiKey string) string {
@@ -87,6 +113,11 @@ func (c *Credentials) SetFacilityID(facilityID string) {
 	c.FacilityID = facilityID
 }
 
+// SetMetro sets the default Metro for the client
+func (c *Credentials) SetMetro(metro string) {
+	c.Metro = metro
+}
+
 // SetAPIKey sets the default APIKey for the client
 func (c *Credentials) SetAPIKey(apiKey string) {
 	c.APIKey = apiKey
@@ -0,0 +1,147 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package device
+
+import (
+	"fmt"
+
+	"github.com/packethost/packngo"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+
+	"github.com/packethost/crossplane-provider-equinix-metal/apis/server/v1alpha2"
+	"github.com/packethost/crossplane-provider-equinix-metal/pkg/clients"
+)
+
+// A ProvisionError explains why a Device cannot be provisioned. Its message is
+// prefixed by its reason, so that the ReconcileError of the Device reports
+// it.
+type ProvisionError struct {
+	Reason  xpv1.ConditionReason
+	Message string
+}
+
+func (e *ProvisionError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Reason, e.Message)
+}
+
+func unprovisionable(reason xpv1.ConditionReason, format string, a ...interface{}) *ProvisionError {
+	return &ProvisionError{Reason: reason, Message: fmt.Sprintf(format, a...)}
+}
+
+// CheckProvisionable returns a *ProvisionError if the plan, operating system
+// and location of the supplied parameters are not offered together by the
+// supplied catalog, or if there is no capacity for the plan in the location.
+// The facility and metro must be the ones the Device is created in. Other
+// errors are returned if the catalog cannot be listed.
+func CheckProvisionable(c clients.CatalogClient, fp v1alpha2.DeviceParameters) error { //nolint:gocyclo
+	plan, err := c.Plan(fp.Plan)
+	if err != nil {
+		return err
+	}
+	if plan == nil {
+		return unprovisionable(v1alpha2.ReasonPlanUnavailable, "plan %q does not exist", fp.Plan)
+	}
+
+	os, err := c.OperatingSystem(fp.OS)
+	if err != nil {
+		return err
+	}
+	if os == nil {
+		return unprovisionable(v1alpha2.ReasonOperatingSystemIncompatible, "operating system %q does not exist", fp.OS)
+	}
+	if len(os.ProvisionableOn) > 0 && !contains(os.ProvisionableOn, plan.Slug) {
+		return unprovisionable(v1alpha2.ReasonOperatingSystemIncompatible, "operating system %q cannot be provisioned on plan %q", fp.OS, fp.Plan)
+	}
+
+	facility := fp.Facility
+	if facility == clients.FacilityAny {
+		facility = ""
+	}
+
+	switch {
+	case facility != "":
+		if len(plan.AvailableIn) > 0 && !inFacilities(plan.AvailableIn, facility) {
+			return unprovisionable(v1alpha2.ReasonLocationUnavailable, "plan %q is not offered in facility %q", fp.Plan, facility)
+		}
+	case fp.Metro != "":
+		m, err := c.Metro(fp.Metro)
+		if err != nil {
+			return err
+		}
+		if m == nil {
+			return unprovisionable(v1alpha2.ReasonLocationUnavailable, "metro %q does not exist", fp.Metro)
+		}
+		if len(plan.AvailableInMetros) > 0 && !inMetros(plan.AvailableInMetros, fp.Metro) {
+			return unprovisionable(v1alpha2.ReasonLocationUnavailable, "plan %q is not offered in metro %q", fp.Plan, fp.Metro)
+		}
+	default:
+		return nil
+	}
+
+	// Reserved hardware does not draw on the capacity of the location.
+	if fp.HardwareReservationID != nil && *fp.HardwareReservationID != "" {
+		return nil
+	}
+
+	metro := fp.Metro
+	if facility != "" {
+		metro = ""
+	}
+	level, err := c.CapacityLevel(fp.Plan, facility, metro)
+	if err != nil {
+		return err
+	}
+	if level == clients.CapacityUnavailable {
+		return unprovisionable(v1alpha2.ReasonInsufficientCapacity, "no capacity for plan %q in %s", fp.Plan, location(facility, metro))
+	}
+	return nil
+}
+
+func location(facility, metro string) string {
+	if metro != "" {
+		return fmt.Sprintf("metro %q", metro)
+	}
+	return fmt.Sprintf("facility %q", facility)
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+func inMetros(metros []packngo.Metro, code string) bool {
+	for _, m := range metros {
+		if m.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+func inFacilities(facilities []packngo.Facility, code string) bool {
+	for _, f := range facilities {
+		if f.Code == code {
+			return true
+		}
+	}
+	return false
+}
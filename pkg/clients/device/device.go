@@ -18,15 +18,23 @@ package device
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
-	"reflect"
+	"path"
+	"strings"
+	"time"
 
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/packethost/packngo"
 	"github.com/pkg/errors"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
 	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 
 	"github.com/packethost/crossplane-provider-equinix-metal/apis/server/v1alpha2"
 	"github.com/packethost/crossplane-provider-equinix-metal/pkg/clients"
@@ -53,6 +61,48 @@ type PortsClient interface {
 	ConvertDevice(*packngo.Device, string) error
 }
 
+// ActionsClient implements the Equinix Metal API device actions that packngo
+// does not, for the Equinix Metal Crossplane Provider
+type ActionsClient interface {
+	Reinstall(deviceID string, r *ReinstallRequest) (*packngo.Response, error)
+	Rescue(deviceID string) (*packngo.Response, error)
+	Reboot(deviceID string) (*packngo.Response, error)
+}
+
+// An actionRequest performs an action on a device that takes no options.
+type actionRequest struct {
+	Type string `json:"type"`
+}
+
+// A ReinstallRequest reinstalls the operating system of a device.
+type ReinstallRequest struct {
+	Type            string `json:"type"`
+	OperatingSystem string `json:"operating_system,omitempty"`
+	PreserveData    bool   `json:"preserve_data"`
+}
+
+// NewActionsClient returns an ActionsClient using the supplied Equinix Metal
+// API client.
+func NewActionsClient(c *packngo.Client) ActionsClient {
+	return actionsClient{client: c}
+}
+
+type actionsClient struct {
+	client *packngo.Client
+}
+
+func (c actionsClient) Reinstall(deviceID string, r *ReinstallRequest) (*packngo.Response, error) {
+	return c.client.DoRequest("POST", path.Join("devices", deviceID, "actions"), r, nil)
+}
+
+func (c actionsClient) Rescue(deviceID string) (*packngo.Response, error) {
+	return c.client.DoRequest("POST", path.Join("devices", deviceID, "actions"), &actionRequest{Type: "rescue"}, nil)
+}
+
+func (c actionsClient) Reboot(deviceID string) (*packngo.Response, error) {
+	return c.client.DoRequest("POST", path.Join("devices", deviceID, "actions"), &actionRequest{Type: "reboot"}, nil)
+}
+
 // build-time test that the interface is implemented
 var _ Client = (&packngo.Client{}).Devices
 var _ PortsClient = (&packngo.Client{}).DevicePorts //nolint:staticcheck
@@ -62,6 +112,9 @@ var _ PortsClient = (&packngo.Client{}).DevicePorts //nolint:staticcheck
 type ClientWithDefaults interface {
 	Client
 	PortsClient
+	NetworkClient
+	ActionsClient
+	IPAddressClient
 	clients.DefaultGetter
 }
 
@@ -69,6 +122,9 @@ type ClientWithDefaults interface {
 type CredentialedClient struct {
 	Client
 	PortsClient
+	NetworkClient
+	ActionsClient
+	IPAddressClient
 	*clients.Credentials
 }
 
@@ -81,17 +137,29 @@ func NewClient(ctx context.Context, config *clients.Credentials) (ClientWithDefa
 	if err != nil {
 		return nil, err
 	}
-	deviceClient := CredentialedClient{
-		Client:      client.Client.Devices,
-		PortsClient: client.Client.DevicePorts, //nolint:staticcheck
-		Credentials: client.Credentials,
-	}
-	deviceClient.SetProjectID(config.ProjectID)
-	return deviceClient, nil
+	return NewClientFromAPI(ctx, client)
 }
 
-// CreateFromDevice return packngo.DeviceCreateRequest created from Kubernetes
+// NewClientFromAPI returns a Client implementing the Equinix Metal API methods
+// needed to interact with Devices, using an existing Equinix Metal API client
+func NewClientFromAPI(_ context.Context, client *clients.Client) (ClientWithDefaults, error) {
+	return CredentialedClient{
+		Client:          client.Client.Devices,
+		PortsClient:     client.Client.DevicePorts, //nolint:staticcheck
+		NetworkClient:   client.Client.Ports,
+		ActionsClient:   NewActionsClient(client.Client),
+		IPAddressClient: NewIPAddressClient(client.Client),
+		Credentials:     client.Credentials,
+	}, nil
+}
+
+// CreateFromDevice return packngo.DeviceCreateRequest created from Kubernetes.
+// The Device is created in the supplied project unless it specifies another.
 func CreateFromDevice(d *v1alpha2.Device, projectID string) *packngo.DeviceCreateRequest {
+	if d.Spec.ForProvider.ProjectID != "" {
+		projectID = d.Spec.ForProvider.ProjectID
+	}
+
 	ips := []packngo.IPAddressCreateRequest{}
 	for _, ip := range d.Spec.ForProvider.IPAddresses {
 		ips = append(ips, packngo.IPAddressCreateRequest{
@@ -105,15 +173,14 @@ func CreateFromDevice(d *v1alpha2.Device, projectID string) *packngo.DeviceCreat
 	r := &packngo.DeviceCreateRequest{
 		Hostname:              emptyIfNil(d.Spec.ForProvider.Hostname),
 		Plan:                  d.Spec.ForProvider.Plan,
-		Facility:              []string{d.Spec.ForProvider.Facility},
-		Metro:                 d.Spec.ForProvider.Metro,
 		OS:                    d.Spec.ForProvider.OS,
 		BillingCycle:          emptyIfNil(d.Spec.ForProvider.BillingCycle),
 		ProjectID:             projectID,
 		UserData:              emptyIfNil(d.Spec.ForProvider.UserData),
-		Tags:                  d.Spec.ForProvider.Tags,
+		Tags:                  Tags(d),
 		IPAddresses:           ips,
-		CustomData:            emptyIfNil(d.Spec.ForProvider.CustomData),
+		Description:           emptyIfNil(d.Spec.ForProvider.Description),
+		CustomData:            CustomDataString(d.Spec.ForProvider.CustomData),
 		IPXEScriptURL:         emptyIfNil(d.Spec.ForProvider.IPXEScriptURL),
 		PublicIPv4SubnetSize:  zeroIfNil(d.Spec.ForProvider.PublicIPv4SubnetSize),
 		AlwaysPXE:             falseIfNil(d.Spec.ForProvider.AlwaysPXE),
@@ -121,12 +188,23 @@ func CreateFromDevice(d *v1alpha2.Device, projectID string) *packngo.DeviceCreat
 		Features:              d.Spec.ForProvider.Features,
 		UserSSHKeys:           d.Spec.ForProvider.UserSSHKeys,
 		ProjectSSHKeys:        d.Spec.ForProvider.ProjectSSHKeys,
+		TerminationTime:       timestamp(TerminationTime(d)),
+		Storage:               StorageRequest(d.Spec.ForProvider.Storage),
 
 		// TODO:
-		// Storage
 		// SpotInstance
 		// SpotPriceMax
-		// TerminationTime
+	}
+
+	// A metro late-initialized for a Device created in a facility is omitted,
+	// so that the Device is recreated where it was first created.
+	switch fp := d.Spec.ForProvider; {
+	case fp.Facility == "":
+		r.Metro = fp.Metro
+	case fp.Facility == clients.FacilityAny:
+		r.Facility, r.Metro = []string{fp.Facility}, fp.Metro
+	default:
+		r.Facility = []string{fp.Facility}
 	}
 
 	return r
@@ -154,53 +232,167 @@ func falseIfNil(in *bool) bool {
 }
 
 // GetConnectionDetails extracts managed.ConnectionDetails out of
-// packngo.Device.
-func GetConnectionDetails(device *packngo.Device) managed.ConnectionDetails {
+// packngo.Device, including the serial over SSH console of the device if the
+// supplied Device enables it.
+func GetConnectionDetails(d *v1alpha2.Device, device *packngo.Device) managed.ConnectionDetails {
+	cd := SOSConnectionDetails(d, device)
+
 	// RootPassword is only in the device responses for 24h
 	// TODO(displague) Handle devices without public IPv4
 	if device.RootPassword == "" || device.GetNetworkInfo().PublicIPv4 == "" {
-		return managed.ConnectionDetails{}
+		return cd
 	}
 
 	// TODO(displague) device.User is in the API but not included in packngo
 	user := "root"
 	port := "22" // ssh
 
-	return managed.ConnectionDetails{
-		xpv1.ResourceCredentialsSecretEndpointKey: []byte(device.GetNetworkInfo().PublicIPv4),
-		xpv1.ResourceCredentialsSecretUserKey:     []byte(user),
-		xpv1.ResourceCredentialsSecretPasswordKey: []byte(device.RootPassword),
-		xpv1.ResourceCredentialsSecretPortKey:     []byte(port),
+	cd[xpv1.ResourceCredentialsSecretEndpointKey] = []byte(device.GetNetworkInfo().PublicIPv4)
+	cd[xpv1.ResourceCredentialsSecretUserKey] = []byte(user)
+	cd[xpv1.ResourceCredentialsSecretPasswordKey] = []byte(device.RootPassword)
+	cd[xpv1.ResourceCredentialsSecretPortKey] = []byte(port)
+	return cd
+}
+
+// Connection secret keys of the serial over SSH (SOS) console of a device.
+const (
+	ConnectionSOSEndpointKey = "sosEndpoint"
+	ConnectionSOSUserKey     = "sosUsername"
+	ConnectionSOSPortKey     = "sosPort"
+)
+
+// SOSHostname returns the hostname of the serial over SSH console of devices
+// in the supplied metro.
+func SOSHostname(metro string) string {
+	return "sos." + metro + ".platformequinix.com"
+}
+
+// SOSConnectionDetails returns the connection details of the serial over SSH
+// console of the supplied device, which is reached by connecting to the SOS
+// hostname of its metro as the short ID of the device. No details are
+// returned unless the supplied Device enables them, or before the short ID
+// and metro of the device are known.
+func SOSConnectionDetails(d *v1alpha2.Device, device *packngo.Device) managed.ConnectionDetails {
+	cd := managed.ConnectionDetails{}
+	metro := strings.ToLower(clients.MetroCode(device.Metro, device.Facility))
+	if !d.Spec.ForProvider.SerialOverSSH || device.ShortID == "" || metro == "" {
+		return cd
+	}
+	cd[ConnectionSOSEndpointKey] = []byte(SOSHostname(metro))
+	cd[ConnectionSOSUserKey] = []byte(device.ShortID)
+	cd[ConnectionSOSPortKey] = []byte("22")
+	return cd
+}
+
+// Connection secret keys of the rescue mode of a device.
+const (
+	ConnectionRescueKey         = "rescue"
+	ConnectionRescueUserKey     = "rescueUsername"
+	ConnectionRescuePasswordKey = "rescuePassword"
+)
+
+// RescueOS is the slug of the operating system a device runs in rescue mode.
+const RescueOS = "alpine_3"
+
+// Rescued returns true if the supplied device is running the rescue operating
+// system.
+func Rescued(device *packngo.Device) bool {
+	return device.OS != nil && device.OS.Slug == RescueOS
+}
+
+// RescueTimeout is how long a requested rescue mode change is waited for
+// before it is requested again.
+const RescueTimeout = 15 * time.Minute
+
+// RescueInProgress returns true while the device of the supplied observation
+// is changing its rescue mode, either because a requested change has neither
+// been observed nor timed out, or because the device is in a transitional
+// state. Changes are not requested again while one is in progress, since each
+// reboots the device.
+func RescueInProgress(o v1alpha2.DeviceObservation, now time.Time) bool {
+	switch o.State {
+	case v1alpha2.StateQueued,
+		v1alpha2.StateProvisioning,
+		v1alpha2.StatePoweringOn,
+		v1alpha2.StatePoweringOff,
+		v1alpha2.StateReinstalling,
+		v1alpha2.StateDeprovisioning:
+		return true
+	}
+	a := o.RescueAction
+	if a == nil || (a.Action == v1alpha2.RescueActionRescue) == o.Rescued {
+		return false
 	}
+	return now.Before(a.RequestedAt.Add(RescueTimeout))
+}
+
+// RescueConnectionDetails returns the connection details of a device that is
+// in rescue mode, with the temporary root password of the rescue operating
+// system, or of one that left it, clearing that password. The password of the
+// device itself is not returned, so that it is kept while the device is in
+// rescue mode.
+func RescueConnectionDetails(device *packngo.Device, rescued bool) managed.ConnectionDetails {
+	if !rescued {
+		return managed.ConnectionDetails{
+			ConnectionRescueKey:         []byte("false"),
+			ConnectionRescuePasswordKey: []byte{},
+		}
+	}
+	cd := managed.ConnectionDetails{
+		ConnectionRescueKey:     []byte("true"),
+		ConnectionRescueUserKey: []byte("root"),
+	}
+	if device.RootPassword != "" {
+		cd[ConnectionRescuePasswordKey] = []byte(device.RootPassword)
+	}
+	if ip := device.GetNetworkInfo().PublicIPv4; ip != "" {
+		cd[xpv1.ResourceCredentialsSecretEndpointKey] = []byte(ip)
+	}
+	return cd
 }
 
 // GenerateObservation produces v1alpha2.DeviceObservation from packngo.Device
 func GenerateObservation(device *packngo.Device) (v1alpha2.DeviceObservation, error) {
 	// Update device status
 	observation := v1alpha2.DeviceObservation{
-		ID:     device.ID,
-		Href:   device.Href,
-		State:  device.State,
-		Locked: device.Locked,
-		IPv4:   device.GetNetworkInfo().PublicIPv4,
+		ID:        device.ID,
+		Href:      device.Href,
+		ShortID:   device.ShortID,
+		ProjectID: clients.ProjectID(device.Project),
+		State:     device.State,
+		Locked:    device.Locked,
+		Rescued:   Rescued(device),
+		IPv4:      device.GetNetworkInfo().PublicIPv4,
+
+		UserDataHash: UserDataHash(device.UserData),
+	}
+
+	if device.TerminationTime != nil {
+		t := metav1.NewTime(device.TerminationTime.Time)
+		observation.TerminationTime = &t
 	}
 
 	if device.Facility != nil {
 		observation.Facility = device.Facility.Code
 	}
+	observation.Metro = clients.MetroCode(device.Metro, device.Facility)
 
 	// TODO: investigate better way to do this
 	observation.ProvisionPercentage = apiresource.MustParse(fmt.Sprintf("%.6f", device.ProvisionPer))
 
-	if !observation.CreatedAt.IsZero() {
-		if err := observation.CreatedAt.UnmarshalText([]byte(device.Created)); err != nil {
+	if device.Created != "" {
+		t := metav1.Time{}
+		if err := t.UnmarshalText([]byte(device.Created)); err != nil {
 			return v1alpha2.DeviceObservation{}, errors.Wrap(err, errUnmarshalDate)
 		}
+		observation.CreatedAt = &t
 	}
-	if !observation.UpdatedAt.IsZero() {
-		if err := observation.UpdatedAt.UnmarshalText([]byte(device.Updated)); err != nil {
+	if device.Updated != "" {
+		t := metav1.Time{}
+		if err := t.UnmarshalText([]byte(device.Updated)); err != nil {
 			return v1alpha2.DeviceObservation{}, errors.Wrap(err, errUnmarshalDate)
 		}
+		observation.UpdatedAt = &t
 	}
 
 	return observation, nil
@@ -213,7 +405,8 @@ func LateInitialize(in *v1alpha2.DeviceParameters, device *packngo.Device) {
 		return
 	}
 
-	if device.OS != nil {
+	// The operating system of a device in rescue mode is not its own.
+	if device.OS != nil && !Rescued(device) {
 		in.OS = clients.LateInitializeString(in.OS, &device.OS.Slug)
 	}
 
@@ -227,7 +420,14 @@ func LateInitialize(in *v1alpha2.DeviceParameters, device *packngo.Device) {
 	in.Hostname = clients.LateInitializeStringPtr(in.Hostname, &device.Hostname)
 	in.BillingCycle = clients.LateInitializeStringPtr(in.BillingCycle, &device.BillingCycle)
 	in.IPXEScriptURL = clients.LateInitializeStringPtr(in.IPXEScriptURL, &device.IPXEScriptURL)
-	in.UserData = clients.LateInitializeStringPtr(in.UserData, &device.UserData)
+	// Userdata resolved from a reference or sources is not late-initialized,
+	// as it may not be specified together with them. Userdata that was
+	// late-initialized before they were specified is removed.
+	if ResolvesUserData(*in) {
+		in.UserData = nil
+	} else {
+		in.UserData = clients.LateInitializeStringPtr(in.UserData, &device.UserData)
+	}
 	in.AlwaysPXE = clients.LateInitializeBoolPtr(in.AlwaysPXE, &device.AlwaysPXE)
 	in.Locked = clients.LateInitializeBoolPtr(in.Locked, &device.Locked)
 
@@ -237,36 +437,46 @@ func LateInitialize(in *v1alpha2.DeviceParameters, device *packngo.Device) {
 		}
 	}
 
-	// We don't LateInitialize metro because facility and metro are incompatible API create options
-	// in.Metro = clients.LateInitializeString(in.Metro, &device.Metro.Code)
+	// Metro is late-initialized so that Devices created in a facility may
+	// migrate to specifying only their metro. Facility is not, as it may be
+	// "any" and AtProvider.Facility reflects the facility that was chosen.
+	metro := clients.MetroCode(device.Metro, device.Facility)
+	in.Metro = clients.LateInitializeString(in.Metro, &metro)
 
-	// Facility is required with a supported "any" value
-	// AtProvider.Facility will reflect the Equinix Metal selected
-
-	// TODO(displague) CustomData is string on input and a map when fetched
-	// What's the format? Should it always be a map in k8s?
-	// in.CustomData = device.CustomData
-
-	// TODO(displague) Description is not yet supported
-	// in.Description = device.Description
+	in.Description = clients.LateInitializeStringPtr(in.Description, device.Description)
+	if in.CustomData == nil && len(device.CustomData) > 0 {
+		if raw, err := json.Marshal(device.CustomData); err == nil {
+			in.CustomData = &runtime.RawExtension{Raw: raw}
+		}
+	}
 
+	// Tags owned by the provider are added to every request, so they are not
+	// late-initialized into the user's tags.
 	if in.Tags == nil {
-		in.Tags = device.Tags
+		in.Tags = clients.UserTags(device.Tags)
 	}
 }
 
 // IsUpToDate returns true if the supplied Kubernetes resource does not differ
 // from the supplied Equinix Metal resource. It considers only fields that can be
 // modified in place without deleting and recreating the instance, which are
-// immutable.
-func IsUpToDate(d *v1alpha2.Device, p *packngo.Device) (upToDate bool, networkTypeUpToDate bool) {
+// immutable. The supplied userdata, resolved from the references or sources of
+// the Kubernetes resource, is compared by its hash with the observed userdata
+// hash in its status, rather than the userdata of its spec. It is not compared
+// if it is nil. IP addresses are matched to the supplied reservations they are
+// drawn from, or regardless of their reservations if they are nil.
+func IsUpToDate(d *v1alpha2.Device, p *packngo.Device, userdata *string, reservations IPReservations) (upToDate bool, networkTypeUpToDate bool) {
 	networkType := p.GetNetworkType()
 	networkIsUpToDate := nilOrEqualStr(d.Spec.ForProvider.NetworkType, networkType)
 
 	if !nilOrEqualStr(d.Spec.ForProvider.Hostname, p.Hostname) {
 		return false, networkIsUpToDate
 	}
-	if !nilOrEqualStr(d.Spec.ForProvider.UserData, p.UserData) {
+	if ResolvesUserData(d.Spec.ForProvider) {
+		if userdata != nil && UserDataHash(*userdata) != d.Status.AtProvider.UserDataHash {
+			return false, networkIsUpToDate
+		}
+	} else if !nilOrEqualStr(d.Spec.ForProvider.UserData, p.UserData) {
 		return false, networkIsUpToDate
 	}
 	if !nilOrEqualStr(d.Spec.ForProvider.IPXEScriptURL, p.IPXEScriptURL) {
@@ -281,20 +491,32 @@ func IsUpToDate(d *v1alpha2.Device, p *packngo.Device) (upToDate bool, networkTy
 		return false, networkIsUpToDate
 	}
 
-	// TODO(displague) CustomData is string vs map[string]interface{}
-	/* TODO(displague) missing: https://github.com/packethost/packngo/pull/182
-	if d.Spec.ForProvider.Description != p.Description {
-		return false
+	if !nilOrEqualStr(d.Spec.ForProvider.Description, emptyIfNil(p.Description)) {
+		return false, networkIsUpToDate
+	}
+
+	if add, remove := IPAddressChanges(d, p, reservations); len(add) > 0 || len(remove) > 0 {
+		return false, networkIsUpToDate
+	}
+
+	if !CustomDataEqual(d.Spec.ForProvider.CustomData, p.CustomData) {
+		return false, networkIsUpToDate
 	}
-	*/
 
-	if !reflect.DeepEqual(d.Spec.ForProvider.Tags, p.Tags) {
+	if !clients.TagsEqual(Tags(d), p.Tags) {
 		return false, networkIsUpToDate
 	}
 
 	return true, networkIsUpToDate
 }
 
+// ResolvesUserData returns true if the supplied parameters resolve their
+// userdata from ConfigMaps or Secrets, or assemble it from sources, rather than
+// specifying it.
+func ResolvesUserData(fp v1alpha2.DeviceParameters) bool {
+	return fp.UserDataRef != nil || len(fp.UserDataSources) > 0
+}
+
 // nilOrEqualStr is true if a (aPtr) is non-nil and equal to b
 func nilOrEqualStr(aPtr *string, b string) bool {
 	return (aPtr == nil || *aPtr == b)
@@ -305,17 +527,124 @@ func nilOrEqualBool(aPtr *bool, b bool) bool {
 	return (aPtr == nil || *aPtr == b)
 }
 
+// TerminationTime returns when the supplied Device is deleted, as specified
+// by its termination time or TTL, or nil if it is not.
+func TerminationTime(d *v1alpha2.Device) *metav1.Time {
+	fp := d.Spec.ForProvider
+	switch {
+	case fp.TerminationTime != nil:
+		return fp.TerminationTime
+	case fp.TTL != nil:
+		t := metav1.NewTime(d.GetCreationTimestamp().Add(fp.TTL.Duration))
+		return &t
+	}
+	return nil
+}
+
+func timestamp(t *metav1.Time) *packngo.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return &packngo.Timestamp{Time: t.Time}
+}
+
+// UserDataHash returns the hex encoded SHA-256 hash of the supplied userdata,
+// or an empty string if there is no userdata.
+func UserDataHash(userdata string) string {
+	if userdata == "" {
+		return ""
+	}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(userdata)))
+}
+
+// NewReinstallRequest creates a request to reinstall the operating system of
+// the supplied Device, without preserving its data.
+func NewReinstallRequest(d *v1alpha2.Device) *ReinstallRequest {
+	return &ReinstallRequest{
+		Type:            "reinstall",
+		OperatingSystem: d.Spec.ForProvider.OS,
+	}
+}
+
+// Tags returns the tags of the supplied Device: its user tags, and the tags
+// that trace it back to its Kubernetes owners.
+func Tags(d *v1alpha2.Device) []string {
+	return clients.MergeTags(d.Spec.ForProvider.Tags, clients.ManagedTags(d))
+}
+
 // NewUpdateDeviceRequest creates a request to update an instance suitable for
 // use with the Equinix Metal API.
 func NewUpdateDeviceRequest(d *v1alpha2.Device) *packngo.DeviceUpdateRequest {
+	tags := Tags(d)
 	return &packngo.DeviceUpdateRequest{
 		Hostname:      d.Spec.ForProvider.Hostname,
 		Locked:        d.Spec.ForProvider.Locked,
 		UserData:      d.Spec.ForProvider.UserData,
 		IPXEScriptURL: d.Spec.ForProvider.IPXEScriptURL,
 		AlwaysPXE:     d.Spec.ForProvider.AlwaysPXE,
-		Tags:          &d.Spec.ForProvider.Tags,
+		Tags:          &tags,
 		Description:   d.Spec.ForProvider.Description,
-		CustomData:    d.Spec.ForProvider.CustomData,
+		CustomData:    customDataStringPtr(d.Spec.ForProvider.CustomData),
+	}
+}
+
+// customData returns the JSON document of the supplied custom data. A JSON
+// string is taken to contain the document, as custom data was once specified
+// as a string.
+func customData(c *runtime.RawExtension) []byte {
+	if c == nil || len(c.Raw) == 0 {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(c.Raw, &s); err == nil {
+		return []byte(s)
+	}
+	return c.Raw
+}
+
+// CustomDataString returns the supplied custom data as the JSON string the
+// Equinix Metal API accepts, or an empty string if there is none.
+func CustomDataString(c *runtime.RawExtension) string {
+	return string(customData(c))
+}
+
+func customDataStringPtr(c *runtime.RawExtension) *string {
+	if c == nil {
+		return nil
 	}
+	s := CustomDataString(c)
+	return &s
+}
+
+// CustomDataEqual returns true if the supplied custom data is unspecified, or
+// is semantically equal to the observed custom data of a device.
+func CustomDataEqual(c *runtime.RawExtension, observed map[string]interface{}) bool {
+	if c == nil {
+		return true
+	}
+
+	var want interface{} = map[string]interface{}{}
+	if data := customData(c); len(data) > 0 {
+		if err := json.Unmarshal(data, &want); err != nil {
+			return false
+		}
+	}
+	if want == nil {
+		want = map[string]interface{}{}
+	}
+
+	// The observed custom data is round-tripped through JSON so that its
+	// numbers and nested values have the same types as the desired data.
+	var got interface{} = map[string]interface{}{}
+	if len(observed) > 0 {
+		raw, err := json.Marshal(observed)
+		if err != nil {
+			return false
+		}
+		if err := json.Unmarshal(raw, &got); err != nil {
+			return false
+		}
+	}
+
+	return cmp.Equal(want, got, cmpopts.EquateEmpty())
 }
@@ -0,0 +1,241 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package device
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/packethost/packngo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+
+	"github.com/packethost/crossplane-provider-equinix-metal/apis/server/v1alpha2"
+)
+
+func TestCustomDataEqual(t *testing.T) {
+	type args struct {
+		c        *runtime.RawExtension
+		observed map[string]interface{}
+	}
+
+	cases := map[string]struct {
+		args args
+		want bool
+	}{
+		"Unspecified": {
+			args: args{observed: map[string]interface{}{"a": "b"}},
+			want: true,
+		},
+		"ReorderedKeys": {
+			args: args{
+				c:        &runtime.RawExtension{Raw: []byte(`{"b": 2, "a": {"c": [1, "x"]}}`)},
+				observed: map[string]interface{}{"a": map[string]interface{}{"c": []interface{}{1, "x"}}, "b": 2},
+			},
+			want: true,
+		},
+		"LegacyString": {
+			args: args{
+				c:        &runtime.RawExtension{Raw: []byte(`"{\"a\":\"b\"}"`)},
+				observed: map[string]interface{}{"a": "b"},
+			},
+			want: true,
+		},
+		"EmptyObject": {
+			args: args{c: &runtime.RawExtension{Raw: []byte(`{}`)}},
+			want: true,
+		},
+		"Drifted": {
+			args: args{
+				c:        &runtime.RawExtension{Raw: []byte(`{"a": "b"}`)},
+				observed: map[string]interface{}{"a": "c"},
+			},
+			want: false,
+		},
+		"Removed": {
+			args: args{
+				c:        &runtime.RawExtension{Raw: []byte(`{}`)},
+				observed: map[string]interface{}{"a": "b"},
+			},
+			want: false,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := CustomDataEqual(tc.args.c, tc.args.observed); got != tc.want {
+				t.Errorf("CustomDataEqual(...): want %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestGetConnectionDetails(t *testing.T) {
+	observed := &packngo.Device{ShortID: "a1b2c3d4", Metro: &packngo.Metro{Code: "SV"}}
+
+	cases := map[string]struct {
+		sos    bool
+		device *packngo.Device
+		want   managed.ConnectionDetails
+	}{
+		"SOSDisabled": {
+			device: observed,
+			want:   managed.ConnectionDetails{},
+		},
+		"SOSEnabled": {
+			sos:    true,
+			device: observed,
+			want: managed.ConnectionDetails{
+				ConnectionSOSEndpointKey: []byte("sos.sv.platformequinix.com"),
+				ConnectionSOSUserKey:     []byte("a1b2c3d4"),
+				ConnectionSOSPortKey:     []byte("22"),
+			},
+		},
+		"SOSEnabledWithCredentials": {
+			sos: true,
+			device: &packngo.Device{
+				ShortID:      "a1b2c3d4",
+				Facility:     &packngo.Facility{Code: "sv15", Metro: &packngo.Metro{Code: "sv"}},
+				RootPassword: "password",
+				Network: []*packngo.IPAddressAssignment{{IpAddressCommon: packngo.IpAddressCommon{
+					Address: "192.0.2.1", AddressFamily: 4, Public: true, Management: true,
+				}}},
+			},
+			want: managed.ConnectionDetails{
+				ConnectionSOSEndpointKey:                  []byte("sos.sv.platformequinix.com"),
+				ConnectionSOSUserKey:                      []byte("a1b2c3d4"),
+				ConnectionSOSPortKey:                      []byte("22"),
+				xpv1.ResourceCredentialsSecretEndpointKey: []byte("192.0.2.1"),
+				xpv1.ResourceCredentialsSecretUserKey:     []byte("root"),
+				xpv1.ResourceCredentialsSecretPasswordKey: []byte("password"),
+				xpv1.ResourceCredentialsSecretPortKey:     []byte("22"),
+			},
+		},
+		"SOSEnabledWithoutShortID": {
+			sos:    true,
+			device: &packngo.Device{Metro: &packngo.Metro{Code: "sv"}},
+			want:   managed.ConnectionDetails{},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d := &v1alpha2.Device{}
+			d.Spec.ForProvider.SerialOverSSH = tc.sos
+			got := GetConnectionDetails(d, tc.device)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("GetConnectionDetails(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIsUpToDate(t *testing.T) {
+	stale, resolved := "#cloud-config\nstale", "#cloud-config\nresolved"
+	ref := &v1alpha2.DataKeySelector{NamespacedName: v1alpha2.NamespacedName{Namespace: "default", Name: "userdata"}, Kind: "Secret"}
+
+	cases := map[string]struct {
+		fp       v1alpha2.DeviceParameters
+		hash     string
+		userdata *string
+		want     bool
+	}{
+		"UserDataUpToDate": {
+			fp:   v1alpha2.DeviceParameters{UserData: &resolved},
+			want: true,
+		},
+		"UserDataChanged": {
+			fp:   v1alpha2.DeviceParameters{UserData: &stale},
+			want: false,
+		},
+		"ResolvedUserDataUpToDate": {
+			fp:       v1alpha2.DeviceParameters{UserData: &stale, UserDataRef: ref},
+			hash:     UserDataHash(resolved),
+			userdata: &resolved,
+			want:     true,
+		},
+		"ResolvedUserDataChanged": {
+			fp:       v1alpha2.DeviceParameters{UserDataRef: ref},
+			hash:     UserDataHash(stale),
+			userdata: &resolved,
+			want:     false,
+		},
+		"ResolvedUserDataUnresolved": {
+			fp:   v1alpha2.DeviceParameters{UserDataRef: ref},
+			hash: UserDataHash(stale),
+			want: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d := &v1alpha2.Device{}
+			d.Spec.ForProvider = tc.fp
+			d.Status.AtProvider.UserDataHash = tc.hash
+			p := &packngo.Device{UserData: resolved, Tags: Tags(d)}
+
+			got, _ := IsUpToDate(d, p, tc.userdata, nil)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("IsUpToDate(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRescueInProgress(t *testing.T) {
+	now := time.Now()
+	rescue := &v1alpha2.RescueAction{Action: v1alpha2.RescueActionRescue, RequestedAt: metav1.NewTime(now)}
+	expired := &v1alpha2.RescueAction{Action: v1alpha2.RescueActionRescue, RequestedAt: metav1.NewTime(now.Add(-RescueTimeout))}
+
+	cases := map[string]struct {
+		o    v1alpha2.DeviceObservation
+		want bool
+	}{
+		"NoAction": {
+			o:    v1alpha2.DeviceObservation{State: v1alpha2.StateActive},
+			want: false,
+		},
+		"Transitioning": {
+			o:    v1alpha2.DeviceObservation{State: v1alpha2.StatePoweringOn},
+			want: true,
+		},
+		"Pending": {
+			o:    v1alpha2.DeviceObservation{State: v1alpha2.StateActive, RescueAction: rescue},
+			want: true,
+		},
+		"Completed": {
+			o:    v1alpha2.DeviceObservation{State: v1alpha2.StateActive, Rescued: true, RescueAction: rescue},
+			want: false,
+		},
+		"TimedOut": {
+			o:    v1alpha2.DeviceObservation{State: v1alpha2.StateActive, RescueAction: expired},
+			want: false,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := RescueInProgress(tc.o, now); got != tc.want {
+				t.Errorf("RescueInProgress(...): want %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
@@ -19,6 +19,7 @@ package fake
 import (
 	"github.com/packethost/packngo"
 
+	"github.com/packethost/crossplane-provider-equinix-metal/pkg/clients"
 	"github.com/packethost/crossplane-provider-equinix-metal/pkg/clients/device"
 )
 
@@ -39,8 +40,27 @@ type MockClient struct {
 	MockDeviceNetworkType   func(deviceID string) (string, error)
 	MockConvertDevice       func(*packngo.Device, string) error
 
+	// mock the NetworkClient
+
+	MockBond                func(portID string, bulkEnable bool) (*packngo.Port, *packngo.Response, error)
+	MockDisbond             func(portID string, bulkDisable bool) (*packngo.Port, *packngo.Response, error)
+	MockConvertToLayerTwo   func(portID string) (*packngo.Port, *packngo.Response, error)
+	MockConvertToLayerThree func(portID string, ips []packngo.AddressRequest) (*packngo.Port, *packngo.Response, error)
+
+	MockReinstall func(deviceID string, r *device.ReinstallRequest) (*packngo.Response, error)
+	MockRescue    func(deviceID string) (*packngo.Response, error)
+	MockReboot    func(deviceID string) (*packngo.Response, error)
+
+	// mock the IPAddressClient
+
+	MockAssignIPAddress    func(deviceID, address string) (*packngo.IPAddressAssignment, *packngo.Response, error)
+	MockUnassignIPAddress  func(assignmentID string) (*packngo.Response, error)
+	MockAvailableAddresses func(reservationID string, cidr int) ([]string, *packngo.Response, error)
+	MockGetIPReservation   func(reservationID string) (*packngo.IPAddressReservation, *packngo.Response, error)
+
 	MockGetProjectID  func(string) string
 	MockGetFacilityID func(string) string
+	MockGetMetro      func(string) string
 }
 
 // Create calls the MockClient's MockCreate function.
@@ -55,7 +75,7 @@ func (c *MockClient) Update(deviceID string, createRequest *packngo.DeviceUpdate
 
 // Delete calls the MockClient's MockDelete function.
 func (c *MockClient) Delete(deviceID string, force bool) (*packngo.Response, error) {
-	return c.MockDelete(deviceID, false)
+	return c.MockDelete(deviceID, force)
 }
 
 // Get calls the MockClient's MockGet function.
@@ -73,11 +93,71 @@ func (c *MockClient) DeviceNetworkType(deviceID string) (string, error) {
 	return c.MockDeviceNetworkType(deviceID)
 }
 
+// Bond calls the MockClient's MockBond function.
+func (c *MockClient) Bond(portID string, bulkEnable bool) (*packngo.Port, *packngo.Response, error) {
+	return c.MockBond(portID, bulkEnable)
+}
+
+// Disbond calls the MockClient's MockDisbond function.
+func (c *MockClient) Disbond(portID string, bulkDisable bool) (*packngo.Port, *packngo.Response, error) {
+	return c.MockDisbond(portID, bulkDisable)
+}
+
+// ConvertToLayerTwo calls the MockClient's MockConvertToLayerTwo function.
+func (c *MockClient) ConvertToLayerTwo(portID string) (*packngo.Port, *packngo.Response, error) {
+	return c.MockConvertToLayerTwo(portID)
+}
+
+// ConvertToLayerThree calls the MockClient's MockConvertToLayerThree function.
+func (c *MockClient) ConvertToLayerThree(portID string, ips []packngo.AddressRequest) (*packngo.Port, *packngo.Response, error) {
+	return c.MockConvertToLayerThree(portID, ips)
+}
+
+// Reinstall calls the MockClient's MockReinstall function.
+func (c *MockClient) Reinstall(deviceID string, r *device.ReinstallRequest) (*packngo.Response, error) {
+	return c.MockReinstall(deviceID, r)
+}
+
+// Rescue calls the MockClient's MockRescue function.
+func (c *MockClient) Rescue(deviceID string) (*packngo.Response, error) {
+	return c.MockRescue(deviceID)
+}
+
+// Reboot calls the MockClient's MockReboot function.
+func (c *MockClient) Reboot(deviceID string) (*packngo.Response, error) {
+	return c.MockReboot(deviceID)
+}
+
+// AssignIPAddress calls the MockClient's MockAssignIPAddress function.
+func (c *MockClient) AssignIPAddress(deviceID, address string) (*packngo.IPAddressAssignment, *packngo.Response, error) {
+	return c.MockAssignIPAddress(deviceID, address)
+}
+
+// UnassignIPAddress calls the MockClient's MockUnassignIPAddress function.
+func (c *MockClient) UnassignIPAddress(assignmentID string) (*packngo.Response, error) {
+	return c.MockUnassignIPAddress(assignmentID)
+}
+
+// AvailableAddresses calls the MockClient's MockAvailableAddresses function.
+func (c *MockClient) AvailableAddresses(reservationID string, cidr int) ([]string, *packngo.Response, error) {
+	return c.MockAvailableAddresses(reservationID, cidr)
+}
+
+// GetIPReservation calls the MockClient's MockGetIPReservation function.
+func (c *MockClient) GetIPReservation(reservationID string) (*packngo.IPAddressReservation, *packngo.Response, error) {
+	return c.MockGetIPReservation(reservationID)
+}
+
 // GetFacilityID calls the MockClient's MockGet function.
 func (c *MockClient) GetFacilityID(id string) string {
 	return c.MockGetFacilityID(id)
 }
 
+// GetMetro calls the MockClient's MockGetMetro function.
+func (c *MockClient) GetMetro(metro string) string {
+	return c.MockGetMetro(metro)
+}
+
 // GetProjectID calls the MockClient's MockGet function.
 func (c *MockClient) GetProjectID(id string) string {
 	return c.MockGetProjectID(id)
@@ -87,3 +167,33 @@ func (c *MockClient) GetProjectID(id string) string {
 func (c *MockClient) ConvertDevice(d *packngo.Device, networkType string) error {
 	return c.MockConvertDevice(d, networkType)
 }
+
+var _ clients.CatalogClient = &MockCatalog{}
+
+// MockCatalog is a fake implementation of clients.CatalogClient.
+type MockCatalog struct {
+	MockPlan            func(slug string) (*packngo.Plan, error)
+	MockOperatingSystem func(slug string) (*packngo.OS, error)
+	MockMetro           func(code string) (*packngo.Metro, error)
+	MockCapacityLevel   func(plan, facility, metro string) (string, error)
+}
+
+// Plan calls the MockCatalog's MockPlan function.
+func (c *MockCatalog) Plan(slug string) (*packngo.Plan, error) {
+	return c.MockPlan(slug)
+}
+
+// OperatingSystem calls the MockCatalog's MockOperatingSystem function.
+func (c *MockCatalog) OperatingSystem(slug string) (*packngo.OS, error) {
+	return c.MockOperatingSystem(slug)
+}
+
+// Metro calls the MockCatalog's MockMetro function.
+func (c *MockCatalog) Metro(code string) (*packngo.Metro, error) {
+	return c.MockMetro(code)
+}
+
+// CapacityLevel calls the MockCatalog's MockCapacityLevel function.
+func (c *MockCatalog) CapacityLevel(plan, facility, metro string) (string, error) {
+	return c.MockCapacityLevel(plan, facility, metro)
+}
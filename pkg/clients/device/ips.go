@@ -0,0 +1,180 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package device
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/packethost/packngo"
+	"github.com/pkg/errors"
+
+	"github.com/packethost/crossplane-provider-equinix-metal/apis/server/v1alpha2"
+)
+
+const (
+	errGetIPReservationFmt   = "cannot get IP reservation %s"
+	errParseIPReservationFmt = "cannot parse the address block of IP reservation %s"
+)
+
+// IPAddressClient implements the Equinix Metal API methods needed to manage
+// the IP addresses of created Devices for the Equinix Metal Crossplane Provider
+type IPAddressClient interface {
+	AssignIPAddress(deviceID, address string) (*packngo.IPAddressAssignment, *packngo.Response, error)
+	UnassignIPAddress(assignmentID string) (*packngo.Response, error)
+	AvailableAddresses(reservationID string, cidr int) ([]string, *packngo.Response, error)
+	GetIPReservation(reservationID string) (*packngo.IPAddressReservation, *packngo.Response, error)
+}
+
+// NewIPAddressClient returns an IPAddressClient using the supplied Equinix
+// Metal API client.
+func NewIPAddressClient(c *packngo.Client) IPAddressClient {
+	return ipAddressClient{client: c}
+}
+
+type ipAddressClient struct {
+	client *packngo.Client
+}
+
+func (c ipAddressClient) AssignIPAddress(deviceID, address string) (*packngo.IPAddressAssignment, *packngo.Response, error) {
+	return c.client.DeviceIPs.Assign(deviceID, &packngo.AddressStruct{Address: address})
+}
+
+func (c ipAddressClient) UnassignIPAddress(assignmentID string) (*packngo.Response, error) {
+	return c.client.DeviceIPs.Unassign(assignmentID)
+}
+
+func (c ipAddressClient) AvailableAddresses(reservationID string, cidr int) ([]string, *packngo.Response, error) {
+	return c.client.ProjectIPs.AvailableAddresses(reservationID, &packngo.AvailableRequest{CIDR: cidr})
+}
+
+func (c ipAddressClient) GetIPReservation(reservationID string) (*packngo.IPAddressReservation, *packngo.Response, error) {
+	return c.client.ProjectIPs.Get(reservationID, nil)
+}
+
+// IPReservations are the address blocks of IP reservations, by the ID of the
+// reservation.
+type IPReservations map[string]*net.IPNet
+
+// GetIPReservations returns the address blocks of the reservations the
+// supplied IP addresses are drawn from.
+func GetIPReservations(c IPAddressClient, ips []v1alpha2.IPAddress) (IPReservations, error) {
+	var r IPReservations
+	for _, ip := range ips {
+		for _, id := range ip.Reservations {
+			if _, ok := r[id]; ok {
+				continue
+			}
+			res, _, err := c.GetIPReservation(id)
+			if err != nil {
+				return nil, errors.Wrapf(err, errGetIPReservationFmt, id)
+			}
+			_, block, err := net.ParseCIDR(fmt.Sprintf("%s/%d", res.Network, res.CIDR))
+			if err != nil {
+				return nil, errors.Wrapf(err, errParseIPReservationFmt, id)
+			}
+			if r == nil {
+				r = IPReservations{}
+			}
+			r[id] = block
+		}
+	}
+	return r, nil
+}
+
+// contain returns true if the supplied address is in the block of one of the
+// supplied reservations.
+func (r IPReservations) contain(ids []string, address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, id := range ids {
+		if block, ok := r[id]; ok && block.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// IPAddressChanges returns the IP addresses of the supplied Device that are
+// not assigned to the supplied device, and the assignments of the device that
+// are not among its IP addresses. Assignments match an IP address of the same
+// family and type, of the same CIDR when it is specified, and drawn from one of
+// its reservations when it specifies any and the supplied reservations are not
+// nil. Management addresses are never returned for removal. The IP addresses of a device are
+// only managed once they are specified.
+func IPAddressChanges(d *v1alpha2.Device, p *packngo.Device, reservations IPReservations) (add []v1alpha2.IPAddress, remove []*packngo.IPAddressAssignment) {
+	if len(d.Spec.ForProvider.IPAddresses) == 0 {
+		return nil, nil
+	}
+
+	// Management addresses are matched first, as the first addresses of
+	// each family requested when the device was created become its
+	// management addresses.
+	assigned := make([]*packngo.IPAddressAssignment, 0, len(p.Network))
+	for _, a := range p.Network {
+		if a != nil && a.Management {
+			assigned = append(assigned, a)
+		}
+	}
+	for _, a := range p.Network {
+		if a != nil && !a.Management {
+			assigned = append(assigned, a)
+		}
+	}
+
+	matched := make([]bool, len(assigned))
+	for _, ip := range d.Spec.ForProvider.IPAddresses {
+		found := false
+		for i, a := range assigned {
+			if !matched[i] && ipAddressMatches(ip, a, reservations) {
+				matched[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			add = append(add, ip)
+		}
+	}
+	for i, a := range assigned {
+		if !matched[i] && !a.Management {
+			remove = append(remove, a)
+		}
+	}
+	return add, remove
+}
+
+func ipAddressMatches(ip v1alpha2.IPAddress, a *packngo.IPAddressAssignment, reservations IPReservations) bool {
+	return ip.AddressFamily == a.AddressFamily &&
+		ip.Public == a.Public &&
+		(ip.CIDR == 0 || ip.CIDR == a.CIDR) &&
+		(len(ip.Reservations) == 0 || reservations == nil || reservations.contain(ip.Reservations, a.Address))
+}
+
+// DefaultCIDR returns the CIDR of the supplied IP address, or that of a single
+// address of its family when it is not specified.
+func DefaultCIDR(ip v1alpha2.IPAddress) int {
+	switch {
+	case ip.CIDR != 0:
+		return ip.CIDR
+	case ip.AddressFamily == 6:
+		return 128
+	default:
+		return 32
+	}
+}
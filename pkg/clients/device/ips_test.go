@@ -0,0 +1,122 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package device
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/packethost/packngo"
+
+	"github.com/packethost/crossplane-provider-equinix-metal/apis/server/v1alpha2"
+)
+
+func TestIPAddressChanges(t *testing.T) {
+	assignment := func(family int, public bool, cidr int, management bool) *packngo.IPAddressAssignment {
+		return &packngo.IPAddressAssignment{IpAddressCommon: packngo.IpAddressCommon{
+			ID:            "ip",
+			Address:       "198.51.100.1",
+			AddressFamily: family,
+			Public:        public,
+			CIDR:          cidr,
+			Management:    management,
+		}}
+	}
+	management := []*packngo.IPAddressAssignment{
+		assignment(4, true, 31, true),
+		assignment(4, false, 31, true),
+		assignment(6, true, 127, true),
+	}
+	elastic := v1alpha2.IPAddress{AddressFamily: 4, Public: true, CIDR: 32, Reservations: []string{"r"}}
+	reserved := assignment(4, true, 32, false)
+	reserved.Address = "192.0.2.3"
+	_, block, _ := net.ParseCIDR("192.0.2.0/29")
+	reservations := IPReservations{"r": block}
+
+	type want struct {
+		add    []v1alpha2.IPAddress
+		remove []*packngo.IPAddressAssignment
+	}
+
+	cases := map[string]struct {
+		ips          []v1alpha2.IPAddress
+		network      []*packngo.IPAddressAssignment
+		reservations IPReservations
+		want         want
+	}{
+		"Unspecified": {
+			network: append(management, assignment(4, true, 32, false)),
+			want:    want{},
+		},
+		"ManagementAddressesMatched": {
+			ips: []v1alpha2.IPAddress{
+				{AddressFamily: 4, Public: true},
+				{AddressFamily: 4, Public: false},
+			},
+			network: management,
+			want:    want{},
+		},
+		"AddedAddress": {
+			ips: []v1alpha2.IPAddress{
+				{AddressFamily: 4, Public: true},
+				elastic,
+			},
+			network: management,
+			want:    want{add: []v1alpha2.IPAddress{elastic}},
+		},
+		"RemovedAddress": {
+			ips:     []v1alpha2.IPAddress{{AddressFamily: 4, Public: true}},
+			network: append(management, assignment(4, true, 32, false)),
+			want:    want{remove: []*packngo.IPAddressAssignment{assignment(4, true, 32, false)}},
+		},
+		"ReservedAddressMatched": {
+			ips:          []v1alpha2.IPAddress{{AddressFamily: 4, Public: true}, elastic},
+			reservations: reservations,
+			network:      append(management, reserved),
+			want:         want{},
+		},
+		"AddressFromOtherReservation": {
+			ips:          []v1alpha2.IPAddress{{AddressFamily: 4, Public: true}, elastic},
+			reservations: reservations,
+			network:      append(management, assignment(4, true, 32, false)),
+			want: want{
+				add:    []v1alpha2.IPAddress{elastic},
+				remove: []*packngo.IPAddressAssignment{assignment(4, true, 32, false)},
+			},
+		},
+		"ReservationsNotResolved": {
+			ips:     []v1alpha2.IPAddress{{AddressFamily: 4, Public: true}, elastic},
+			network: append(management, assignment(4, true, 32, false)),
+			want:    want{},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d := &v1alpha2.Device{}
+			d.Spec.ForProvider.IPAddresses = tc.ips
+			add, remove := IPAddressChanges(d, &packngo.Device{Network: tc.network}, tc.reservations)
+			if diff := cmp.Diff(tc.want.add, add); diff != "" {
+				t.Errorf("IPAddressChanges(...): -want add, +got add:\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.want.remove, remove); diff != "" {
+				t.Errorf("IPAddressChanges(...): -want remove, +got remove:\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package device
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/packethost/packngo"
+	"github.com/pkg/errors"
+
+	"github.com/packethost/crossplane-provider-equinix-metal/apis/server/v1alpha2"
+)
+
+const (
+	errUnknownNetworkTypeFmt = "cannot convert to unknown network type %q"
+	errUnknownStepFmt        = "unknown network type conversion step %q"
+	errBondPortFmt           = "cannot bond port %s"
+	errDisbondPortFmt        = "cannot disbond port %s"
+	errConvertPortFmt        = "cannot convert port %s to layer 2"
+	errReassignIPsFmt        = "cannot convert port %s to layer 3"
+
+	bondPortName = "bond0"
+)
+
+// NetworkClient implements the Equinix Metal API port methods needed to
+// convert the network type of Devices for the Equinix Metal Crossplane
+// Provider
+type NetworkClient interface {
+	Bond(portID string, bulkEnable bool) (*packngo.Port, *packngo.Response, error)
+	Disbond(portID string, bulkDisable bool) (*packngo.Port, *packngo.Response, error)
+	ConvertToLayerTwo(portID string) (*packngo.Port, *packngo.Response, error)
+	ConvertToLayerThree(portID string, ips []packngo.AddressRequest) (*packngo.Port, *packngo.Response, error)
+}
+
+// build-time test that the interface is implemented
+var _ NetworkClient = (&packngo.Client{}).Ports
+
+// networkConversionSteps are the steps to convert a device to each network
+// type, in the order they are performed.
+var networkConversionSteps = map[string][]string{
+	packngo.NetworkTypeL3: {
+		v1alpha2.NetworkConversionStepBondPorts,
+		v1alpha2.NetworkConversionStepReassignIPs,
+	},
+	packngo.NetworkTypeHybrid: {
+		v1alpha2.NetworkConversionStepBondPorts,
+		v1alpha2.NetworkConversionStepReassignIPs,
+		v1alpha2.NetworkConversionStepDisbondPorts,
+	},
+	packngo.NetworkTypeL2Individual: {
+		v1alpha2.NetworkConversionStepConvertPorts,
+		v1alpha2.NetworkConversionStepDisbondPorts,
+	},
+	packngo.NetworkTypeL2Bonded: {
+		v1alpha2.NetworkConversionStepConvertPorts,
+		v1alpha2.NetworkConversionStepBondPorts,
+	},
+}
+
+// NewNetworkConversion returns a conversion of a device from one network type
+// to another, with all of its steps pending.
+func NewNetworkConversion(from, to string) (*v1alpha2.NetworkConversion, error) {
+	names, ok := networkConversionSteps[to]
+	if !ok {
+		return nil, errors.Errorf(errUnknownNetworkTypeFmt, to)
+	}
+	c := &v1alpha2.NetworkConversion{From: from, To: to, Steps: make([]v1alpha2.NetworkConversionStep, len(names))}
+	for i, n := range names {
+		c.Steps[i] = v1alpha2.NetworkConversionStep{Name: n, Phase: v1alpha2.NetworkConversionPhasePending}
+	}
+	return c, nil
+}
+
+// NextNetworkConversionStep returns the index of the first step of the
+// supplied conversion that has not completed, or -1 if all have.
+func NextNetworkConversionStep(c *v1alpha2.NetworkConversion) int {
+	for i, s := range c.Steps {
+		if s.Phase != v1alpha2.NetworkConversionPhaseCompleted {
+			return i
+		}
+	}
+	return -1
+}
+
+// ConvertNetworkStep performs the named step of converting the supplied device
+// to the target network type. Each step acts only on the ports that it has not
+// already changed, so that a step that failed or was interrupted may be
+// performed again.
+func ConvertNetworkStep(c NetworkClient, d *packngo.Device, target, step string) error {
+	switch step {
+	case v1alpha2.NetworkConversionStepBondPorts:
+		ports := d.GetBondPorts()
+		if target != packngo.NetworkTypeHybrid {
+			ports = mergePorts(ports, d.GetPhysicalPorts())
+		}
+		for _, p := range sortedPorts(ports) {
+			if p.Data.Bonded {
+				continue
+			}
+			if _, _, err := c.Bond(p.ID, false); err != nil {
+				return errors.Wrapf(err, errBondPortFmt, p.Name)
+			}
+		}
+	case v1alpha2.NetworkConversionStepDisbondPorts:
+		// Hybrid devices keep the first of each pair of ports bonded, while
+		// layer 2 individual devices break the bond of all of them.
+		ports, bulk := d.GetBondPorts(), true
+		if target == packngo.NetworkTypeHybrid {
+			ports, bulk = oddPorts(d.GetPhysicalPorts()), false
+		}
+		for _, p := range sortedPorts(ports) {
+			if !p.Data.Bonded {
+				continue
+			}
+			if _, _, err := c.Disbond(p.ID, bulk); err != nil {
+				return errors.Wrapf(err, errDisbondPortFmt, p.Name)
+			}
+		}
+	case v1alpha2.NetworkConversionStepConvertPorts:
+		ports := d.GetBondPorts()
+		if target == packngo.NetworkTypeL2Individual {
+			ports = namedPorts(ports, bondPortName)
+		}
+		for _, p := range sortedPorts(ports) {
+			if strings.HasPrefix(p.NetworkType, "layer2") {
+				continue
+			}
+			if _, _, err := c.ConvertToLayerTwo(p.ID); err != nil {
+				return errors.Wrapf(err, errConvertPortFmt, p.Name)
+			}
+		}
+	case v1alpha2.NetworkConversionStepReassignIPs:
+		for _, p := range sortedPorts(namedPorts(d.GetBondPorts(), bondPortName)) {
+			if p.NetworkType == packngo.NetworkTypeL3 || p.NetworkType == packngo.NetworkTypeHybrid {
+				continue
+			}
+			ips := []packngo.AddressRequest{
+				{AddressFamily: 4, Public: true},
+				{AddressFamily: 4, Public: false},
+				{AddressFamily: 6, Public: true},
+			}
+			if _, _, err := c.ConvertToLayerThree(p.ID, ips); err != nil {
+				return errors.Wrapf(err, errReassignIPsFmt, p.Name)
+			}
+		}
+	default:
+		return errors.Errorf(errUnknownStepFmt, step)
+	}
+	return nil
+}
+
+func mergePorts(a, b map[string]*packngo.Port) map[string]*packngo.Port {
+	ports := make(map[string]*packngo.Port, len(a)+len(b))
+	for n, p := range a {
+		ports[n] = p
+	}
+	for n, p := range b {
+		ports[n] = p
+	}
+	return ports
+}
+
+func namedPorts(ports map[string]*packngo.Port, name string) map[string]*packngo.Port {
+	named := map[string]*packngo.Port{}
+	if p, ok := ports[name]; ok {
+		named[name] = p
+	}
+	return named
+}
+
+// oddPorts returns the ports whose names end with an odd digit, such as eth1
+// and eth3.
+func oddPorts(ports map[string]*packngo.Port) map[string]*packngo.Port {
+	odd := map[string]*packngo.Port{}
+	for n, p := range ports {
+		if n == "" {
+			continue
+		}
+		if c := n[len(n)-1]; c >= '0' && c <= '9' && (c-'0')%2 == 1 {
+			odd[n] = p
+		}
+	}
+	return odd
+}
+
+// sortedPorts returns the supplied ports ordered by name, so that they are
+// changed in a predictable order.
+func sortedPorts(ports map[string]*packngo.Port) []*packngo.Port {
+	names := make([]string, 0, len(ports))
+	for n := range ports {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	sorted := make([]*packngo.Port, len(names))
+	for i, n := range names {
+		sorted[i] = ports[n]
+	}
+	return sorted
+}
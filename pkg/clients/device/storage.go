@@ -0,0 +1,214 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package device
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/packethost/packngo"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/packethost/crossplane-provider-equinix-metal/apis/server/v1alpha2"
+)
+
+// partitionSize matches the sizes of partitions, such as 512MB, 4GB, a number
+// of sectors, or 0 for the rest of the disk.
+var partitionSize = regexp.MustCompile(`^[0-9]+([KMGT]B?)?$`)
+
+// devicePath matches the paths of disks, partitions and RAID arrays.
+var devicePath = regexp.MustCompile(`^/dev/[A-Za-z0-9_./-]+$`)
+
+// The elements of a packngo.CPR are anonymous structs, which are named here
+// so that they can be built.
+type (
+	cprDisk = struct {
+		Device     string `json:"device"`
+		WipeTable  bool   `json:"wipeTable"`
+		Partitions []struct {
+			Label  string `json:"label"`
+			Number int    `json:"number"`
+			Size   string `json:"size"`
+		} `json:"partitions"`
+	}
+	cprPartition = struct {
+		Label  string `json:"label"`
+		Number int    `json:"number"`
+		Size   string `json:"size"`
+	}
+	cprRAID = struct {
+		Devices []string `json:"devices"`
+		Level   string   `json:"level"`
+		Name    string   `json:"name"`
+	}
+	cprFilesystem = struct {
+		Mount struct {
+			Device string `json:"device"`
+			Format string `json:"format"`
+			Point  string `json:"point"`
+			Create struct {
+				Options []string `json:"options"`
+			} `json:"create"`
+		} `json:"mount"`
+	}
+)
+
+// StorageRequest returns the custom storage layout of the supplied Storage,
+// or nil if it is nil.
+func StorageRequest(s *v1alpha2.Storage) *packngo.CPR {
+	if s == nil {
+		return nil
+	}
+
+	r := &packngo.CPR{}
+	for _, d := range s.Disks {
+		disk := cprDisk{Device: d.Device, WipeTable: d.WipeTable}
+		for _, p := range d.Partitions {
+			disk.Partitions = append(disk.Partitions, cprPartition{Label: p.Label, Number: p.Number, Size: p.Size})
+		}
+		r.Disks = append(r.Disks, disk)
+	}
+	for _, a := range s.RAID {
+		r.Raid = append(r.Raid, cprRAID{Devices: a.Devices, Level: a.Level, Name: a.Name})
+	}
+	for _, f := range s.Filesystems {
+		fs := cprFilesystem{}
+		fs.Mount.Device, fs.Mount.Format, fs.Mount.Point = f.Mount.Device, f.Mount.Format, f.Mount.Point
+		if f.Mount.Create != nil {
+			fs.Mount.Create.Options = f.Mount.Create.Options
+		}
+		r.Filesystems = append(r.Filesystems, fs)
+	}
+	return r
+}
+
+// ValidateStorage returns the errors in the supplied storage layout, at the
+// supplied path: devices it refers to but does not define, partitions that
+// overlap, and devices used more than once. Partitions are laid out in the
+// order of their numbers, so they overlap only if they share a number, or if
+// one other than the last uses the rest of the disk. Whether the partitions
+// fit the disk is not checked, as its size is not known before the Device is
+// provisioned.
+func ValidateStorage(p *field.Path, s *v1alpha2.Storage) field.ErrorList { //nolint:gocyclo
+	if s == nil {
+		return nil
+	}
+
+	var errs field.ErrorList
+	devices := sets.NewString()
+	for i, d := range s.Disks {
+		dp := p.Child("disks").Index(i)
+		errs = append(errs, validateDevicePath(dp.Child("device"), d.Device, devices)...)
+
+		numbers := sets.NewInt()
+		last := 0
+		for _, part := range d.Partitions {
+			if part.Number > last {
+				last = part.Number
+			}
+		}
+		for j, part := range d.Partitions {
+			pp := dp.Child("partitions").Index(j)
+			switch {
+			case part.Number < 1:
+				errs = append(errs, field.Invalid(pp.Child("number"), part.Number, "must be at least 1"))
+			case numbers.Has(part.Number):
+				errs = append(errs, field.Duplicate(pp.Child("number"), part.Number))
+			}
+			numbers.Insert(part.Number)
+			switch {
+			case !partitionSize.MatchString(part.Size):
+				errs = append(errs, field.Invalid(pp.Child("size"), part.Size, "must be a size such as 512MB or 4GB, or 0 for the rest of the disk"))
+			case part.Size == "0" && part.Number != last:
+				errs = append(errs, field.Invalid(pp.Child("size"), part.Size, "only the last partition of a disk may use the rest of the disk"))
+			}
+			devices.Insert(partitionDevice(d.Device, part.Number))
+		}
+	}
+
+	used := sets.NewString()
+	for i, r := range s.RAID {
+		rp := p.Child("raid").Index(i)
+		if len(r.Devices) < 2 {
+			errs = append(errs, field.Invalid(rp.Child("devices"), len(r.Devices), "must contain at least 2 devices"))
+		}
+		for j, d := range r.Devices {
+			errs = append(errs, validateDeviceUse(rp.Child("devices").Index(j), d, devices, used)...)
+		}
+	}
+	// RAID arrays are named once all of their devices are known, so that an
+	// array is not assembled from another.
+	for i, r := range s.RAID {
+		errs = append(errs, validateDevicePath(p.Child("raid").Index(i).Child("name"), r.Name, devices)...)
+	}
+
+	points := sets.NewString()
+	for i, f := range s.Filesystems {
+		mp := p.Child("filesystems").Index(i).Child("mount")
+		errs = append(errs, validateDeviceUse(mp.Child("device"), f.Mount.Device, devices, used)...)
+		if f.Mount.Format == "" {
+			errs = append(errs, field.Required(mp.Child("format"), ""))
+		}
+		switch {
+		case f.Mount.Point == "":
+			errs = append(errs, field.Required(mp.Child("point"), ""))
+		case f.Mount.Point == "none":
+		case points.Has(f.Mount.Point):
+			errs = append(errs, field.Duplicate(mp.Child("point"), f.Mount.Point))
+		}
+		points.Insert(f.Mount.Point)
+	}
+	return errs
+}
+
+// validateDevicePath returns an error if the supplied path is not that of a
+// device, or is already in the supplied set of defined devices.
+func validateDevicePath(p *field.Path, path string, defined sets.String) field.ErrorList {
+	switch {
+	case path == "":
+		return field.ErrorList{field.Required(p, "")}
+	case !devicePath.MatchString(path):
+		return field.ErrorList{field.Invalid(p, path, "must be a device path, such as /dev/sda")}
+	case defined.Has(path):
+		return field.ErrorList{field.Duplicate(p, path)}
+	}
+	defined.Insert(path)
+	return nil
+}
+
+// validateDeviceUse returns an error if the supplied device is not defined, or
+// is already in the supplied set of used devices.
+func validateDeviceUse(p *field.Path, device string, defined, used sets.String) field.ErrorList {
+	switch {
+	case !defined.Has(device):
+		return field.ErrorList{field.NotFound(p, device)}
+	case used.Has(device):
+		return field.ErrorList{field.Duplicate(p, device)}
+	}
+	used.Insert(device)
+	return nil
+}
+
+// partitionDevice returns the path of the numbered partition of the supplied
+// disk, such as /dev/sda1 or /dev/nvme0n1p1.
+func partitionDevice(disk string, number int) string {
+	if disk != "" && disk[len(disk)-1] >= '0' && disk[len(disk)-1] <= '9' {
+		return disk + "p" + strconv.Itoa(number)
+	}
+	return disk + strconv.Itoa(number)
+}
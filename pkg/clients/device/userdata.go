@@ -0,0 +1,227 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package device
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+
+	"github.com/packethost/crossplane-provider-equinix-metal/apis/server/v1alpha2"
+)
+
+// MaxUserDataSize is the largest userdata, in bytes, that the Equinix Metal
+// API accepts.
+const MaxUserDataSize = 64 * 1024
+
+const (
+	errParseTemplate   = "cannot parse userdata template"
+	errExecuteTemplate = "cannot render userdata template"
+	errWritePart       = "cannot write userdata part"
+	errCompressPart    = "cannot compress userdata part"
+
+	errUserDataTooLargeFmt = "userdata is %d bytes, more than the %d bytes the API accepts"
+)
+
+// Content types of multipart userdata.
+const (
+	contentTypeMultipart = "multipart/mixed"
+	contentTypeDefault   = "text/plain"
+	contentTypeGzip      = "application/x-gzip"
+)
+
+// TemplateData is the data a userdata template is executed with. Objects are
+// represented as they are serialized to JSON.
+type TemplateData struct {
+	// Device is the Device the userdata is rendered for, as returned by
+	// TemplateDevice.
+	Device map[string]interface{}
+
+	// Resources are the referenced resources, by the name they are referenced
+	// as.
+	Resources map[string]map[string]interface{}
+
+	// Secrets are the values of the referenced Secrets, by the name they are
+	// referenced as.
+	Secrets map[string]map[string]string
+}
+
+// TemplateDevice returns the supplied Device as its userdata templates see it,
+// created in the supplied location. It is the same whether the Device is being
+// created or has been observed, so that its userdata renders the same: only
+// its name, namespace and labels, and the project, plan, operating system and
+// location of its spec are included. Its status, and fields late-initialized
+// from the API, change once the Device is created and are omitted. The
+// location is the facility if there is one, and the metro otherwise, as the
+// metro of a Device created in a facility is late-initialized.
+func TemplateDevice(d *v1alpha2.Device, facility, metro string) map[string]interface{} {
+	fp := d.Spec.ForProvider
+	forProvider := map[string]interface{}{
+		"plan":            fp.Plan,
+		"operatingSystem": fp.OS,
+	}
+	if fp.ProjectID != "" {
+		forProvider["projectID"] = fp.ProjectID
+	}
+	switch {
+	case facility != "":
+		forProvider["facility"] = facility
+	case metro != "":
+		forProvider["metro"] = metro
+	}
+
+	metadata := map[string]interface{}{"name": d.GetName()}
+	if ns := d.GetNamespace(); ns != "" {
+		metadata["namespace"] = ns
+	}
+	if len(d.GetLabels()) > 0 {
+		labels := make(map[string]interface{}, len(d.GetLabels()))
+		for k, v := range d.GetLabels() {
+			labels[k] = v
+		}
+		metadata["labels"] = labels
+	}
+
+	return map[string]interface{}{
+		"metadata": metadata,
+		"spec":     map[string]interface{}{"forProvider": forProvider},
+	}
+}
+
+// RenderUserData executes the supplied Go template with the supplied data.
+// Referring to a value that does not exist is an error, so that userdata is
+// not rendered before the resources it depends on are observed.
+func RenderUserData(tmpl string, data TemplateData) (string, error) {
+	t, err := template.New("userdata").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", errors.Wrap(err, errParseTemplate)
+	}
+	b := &strings.Builder{}
+	if err := t.Execute(b, data); err != nil {
+		return "", errors.Wrap(err, errExecuteTemplate)
+	}
+	return b.String(), nil
+}
+
+// A UserDataPart is a part of multipart userdata.
+type UserDataPart struct {
+	Content     string
+	ContentType string
+	Filename    string
+}
+
+// MultipartUserData returns a MIME multipart cloud-init document of the
+// supplied parts, optionally compressing each of them. Compressed parts are
+// typed application/x-gzip, which is the only type cloud-init decompresses,
+// and it types the decompressed part from its first line. The document is the
+// same for the same parts, so that it can be compared with observed userdata.
+func MultipartUserData(parts []UserDataPart, compress bool) (string, error) {
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	if err := w.SetBoundary(boundary(parts)); err != nil {
+		return "", errors.Wrap(err, errWritePart)
+	}
+
+	for i, p := range parts {
+		h := textproto.MIMEHeader{}
+		ct := p.ContentType
+		if ct == "" {
+			ct = contentTypeDefault
+		}
+		h.Set("Content-Type", ct+`; charset="utf-8"`)
+		h.Set("MIME-Version", "1.0")
+		filename := p.Filename
+		if filename == "" {
+			filename = fmt.Sprintf("part-%03d", i)
+		}
+		h.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+		content := []byte(p.Content)
+		if compress {
+			z, err := gzipBase64(content)
+			if err != nil {
+				return "", err
+			}
+			h.Set("Content-Type", contentTypeGzip)
+			h.Set("Content-Transfer-Encoding", "base64")
+			content = z
+		}
+
+		pw, err := w.CreatePart(h)
+		if err != nil {
+			return "", errors.Wrap(err, errWritePart)
+		}
+		if _, err := pw.Write(content); err != nil {
+			return "", errors.Wrap(err, errWritePart)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return "", errors.Wrap(err, errWritePart)
+	}
+
+	header := fmt.Sprintf("Content-Type: %s; boundary=%q\r\nMIME-Version: 1.0\r\n\r\n", contentTypeMultipart, w.Boundary())
+	return header + body.String(), nil
+}
+
+// CheckUserDataSize returns an error if the supplied userdata is larger than
+// the API accepts.
+func CheckUserDataSize(userdata string) error {
+	if len(userdata) > MaxUserDataSize {
+		return errors.Errorf(errUserDataTooLargeFmt, len(userdata), MaxUserDataSize)
+	}
+	return nil
+}
+
+// boundary returns a MIME boundary derived from the supplied parts, which is
+// practically certain not to occur in them.
+func boundary(parts []UserDataPart) string {
+	h := sha256.New()
+	for _, p := range parts {
+		_, _ = h.Write([]byte(p.ContentType + "\x00" + p.Filename + "\x00" + p.Content + "\x00"))
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))[:40]
+}
+
+// gzipBase64 returns the supplied content compressed and base64 encoded, in
+// lines of 76 characters as MIME requires.
+func gzipBase64(content []byte) ([]byte, error) {
+	z := &bytes.Buffer{}
+	gw := gzip.NewWriter(z)
+	if _, err := gw.Write(content); err != nil {
+		return nil, errors.Wrap(err, errCompressPart)
+	}
+	if err := gw.Close(); err != nil {
+		return nil, errors.Wrap(err, errCompressPart)
+	}
+
+	enc := base64.StdEncoding.EncodeToString(z.Bytes())
+	b := &strings.Builder{}
+	for len(enc) > 76 {
+		b.WriteString(enc[:76] + "\r\n")
+		enc = enc[76:]
+	}
+	b.WriteString(enc)
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package device
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+
+	"github.com/packethost/crossplane-provider-equinix-metal/apis/server/v1alpha2"
+)
+
+func TestRenderUserData(t *testing.T) {
+	data := TemplateData{
+		Device:    map[string]interface{}{"spec": map[string]interface{}{"forProvider": map[string]interface{}{"hostname": "cool"}}},
+		Resources: map[string]map[string]interface{}{"vlan": {"status": map[string]interface{}{"atProvider": map[string]interface{}{"vxlan": 1000}}}},
+		Secrets:   map[string]map[string]string{"creds": {"password": "secret"}},
+	}
+
+	cases := map[string]struct {
+		tmpl    string
+		want    string
+		wantErr bool
+	}{
+		"Rendered": {
+			tmpl: "{{ .Device.spec.forProvider.hostname }} {{ .Resources.vlan.status.atProvider.vxlan }} {{ .Secrets.creds.password }}",
+			want: "cool 1000 secret",
+		},
+		"MissingValue": {
+			tmpl:    "{{ .Resources.vlan.status.atProvider.vlan }}",
+			wantErr: true,
+		},
+		"InvalidTemplate": {
+			tmpl:    "{{ .Device",
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := RenderUserData(tc.tmpl, data)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("RenderUserData(...): want error %t, got %v", tc.wantErr, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("RenderUserData(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTemplateDevice(t *testing.T) {
+	created := &v1alpha2.Device{}
+	created.SetName("cool")
+	created.SetLabels(map[string]string{"team": "a"})
+	created.Spec.ForProvider = v1alpha2.DeviceParameters{Plan: "c3.small.x86", OS: "ubuntu_20_04", Facility: "sv15"}
+
+	// Observed Devices have late-initialized fields and status.
+	hostname := "observed"
+	observed := created.DeepCopy()
+	observed.Spec.ForProvider.Metro = "sv"
+	observed.Spec.ForProvider.Hostname = &hostname
+	observed.Status.AtProvider.State = v1alpha2.StateActive
+	meta.SetExternalName(observed, "id")
+
+	want := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "cool", "labels": map[string]interface{}{"team": "a"}},
+		"spec": map[string]interface{}{"forProvider": map[string]interface{}{
+			"plan": "c3.small.x86", "operatingSystem": "ubuntu_20_04", "facility": "sv15",
+		}},
+	}
+	if diff := cmp.Diff(want, TemplateDevice(created, "sv15", "")); diff != "" {
+		t.Errorf("TemplateDevice(created): -want, +got:\n%s", diff)
+	}
+	if diff := cmp.Diff(want, TemplateDevice(observed, "sv15", "sv")); diff != "" {
+		t.Errorf("TemplateDevice(observed): -want, +got:\n%s", diff)
+	}
+}
+
+func TestMultipartUserData(t *testing.T) {
+	parts := []UserDataPart{
+		{Content: "#cloud-config\npackages: [vim]\n", ContentType: "text/cloud-config"},
+		{Content: "#!/bin/sh\necho hi\n", Filename: "team.sh"},
+		{Content: "echo typed\n", ContentType: "text/x-shellscript"},
+	}
+
+	cases := map[string]struct {
+		compress bool
+		want     []UserDataPart
+	}{
+		"Plain": {
+			want: []UserDataPart{
+				{Content: parts[0].Content, ContentType: "text/cloud-config", Filename: "part-000"},
+				{Content: parts[1].Content, ContentType: "text/x-shellscript", Filename: "team.sh"},
+				{Content: parts[2].Content, ContentType: "text/x-shellscript", Filename: "part-002"},
+			},
+		},
+		"Compressed": {
+			compress: true,
+			want: []UserDataPart{
+				{Content: parts[0].Content, ContentType: "text/cloud-config", Filename: "part-000"},
+				{Content: parts[1].Content, ContentType: "text/x-shellscript", Filename: "team.sh"},
+				{Content: parts[2].Content, ContentType: "text/plain", Filename: "part-002"},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			userdata, err := MultipartUserData(parts, tc.compress)
+			if err != nil {
+				t.Fatalf("MultipartUserData(...): %v", err)
+			}
+			again, _ := MultipartUserData(parts, tc.compress)
+			if userdata != again {
+				t.Errorf("MultipartUserData(...): not the same for the same parts")
+			}
+			if diff := cmp.Diff(tc.want, parseMultipart(t, userdata)); diff != "" {
+				t.Errorf("MultipartUserData(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCheckUserDataSize(t *testing.T) {
+	if err := CheckUserDataSize(strings.Repeat("a", MaxUserDataSize)); err != nil {
+		t.Errorf("CheckUserDataSize(...): %v", err)
+	}
+	if err := CheckUserDataSize(strings.Repeat("a", MaxUserDataSize+1)); err == nil {
+		t.Errorf("CheckUserDataSize(...): want error, got nil")
+	}
+}
+
+// parseMultipart returns the parts of the supplied multipart userdata, as
+// cloud-init would read them: it only decompresses parts typed
+// application/x-gzip, and types those and text/plain parts from their first
+// line.
+func parseMultipart(t *testing.T, userdata string) []UserDataPart {
+	t.Helper()
+
+	msg, err := mail.ReadMessage(strings.NewReader(userdata))
+	if err != nil {
+		t.Fatalf("mail.ReadMessage(...): %v", err)
+	}
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("mime.ParseMediaType(...): %v", err)
+	}
+
+	var got []UserDataPart
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := r.NextPart()
+		if err != nil {
+			break
+		}
+		ct, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		content, _ := ioutil.ReadAll(p)
+		if p.Header.Get("Content-Transfer-Encoding") == "base64" {
+			if content, err = base64.StdEncoding.DecodeString(strings.ReplaceAll(string(content), "\r\n", "")); err != nil {
+				t.Fatalf("base64.DecodeString(...): %v", err)
+			}
+		}
+		if ct == "application/x-gzip" {
+			zr, err := gzip.NewReader(bytes.NewReader(content))
+			if err != nil {
+				t.Fatalf("gzip.NewReader(...): %v", err)
+			}
+			content, _ = ioutil.ReadAll(zr)
+			ct = "text/plain"
+		}
+		if ct == "text/plain" {
+			ct = typeFromStartsWith(string(content), ct)
+		}
+		got = append(got, UserDataPart{Content: string(content), ContentType: ct, Filename: p.FileName()})
+	}
+	return got
+}
+
+// typeFromStartsWith returns the content type cloud-init gives content by its
+// first line, or the supplied content type if it gives it none.
+func typeFromStartsWith(content, ct string) string {
+	for _, t := range []struct{ prefix, ct string }{
+		{"#include", "text/x-include-url"},
+		{"#cloud-config", "text/cloud-config"},
+		{"#cloud-boothook", "text/cloud-boothook"},
+		{"#part-handler", "text/part-handler"},
+		{"#!", "text/x-shellscript"},
+	} {
+		if strings.HasPrefix(content, t.prefix) {
+			return t.ct
+		}
+	}
+	return ct
+}
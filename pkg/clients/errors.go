@@ -0,0 +1,160 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clients
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/packethost/packngo"
+	"github.com/pkg/errors"
+)
+
+// ErrorClass describes how an error returned while calling the Equinix Metal
+// API should be handled.
+type ErrorClass string
+
+// Error classes.
+const (
+	// ErrorClassNone is the class of a nil error.
+	ErrorClassNone ErrorClass = ""
+
+	// ErrorClassThrottled errors were rejected by API rate limiting and
+	// should be retried once the rate limit resets.
+	ErrorClassThrottled ErrorClass = "Throttled"
+
+	// ErrorClassTransient errors are server or network failures that are
+	// expected to resolve without intervention.
+	ErrorClassTransient ErrorClass = "Transient"
+
+	// ErrorClassQuota errors were rejected because an organization or project
+	// limit was reached. They will not resolve until the limit is raised or
+	// other resources are released.
+	ErrorClassQuota ErrorClass = "QuotaExceeded"
+
+	// ErrorClassPermanent errors will recur until the request is changed.
+	ErrorClassPermanent ErrorClass = "Permanent"
+)
+
+// Suggested requeue delays for classified errors that do not carry their own
+// retry instructions.
+const (
+	throttledRequeueAfter = 30 * time.Second
+	transientRequeueAfter = 15 * time.Second
+	quotaRequeueAfter     = 10 * time.Minute
+)
+
+// ClassifyError returns the ErrorClass of the supplied error.
+func ClassifyError(err error) ErrorClass { //nolint:gocyclo
+	if err == nil {
+		return ErrorClassNone
+	}
+
+	var e *packngo.ErrorResponse
+	if errors.As(err, &e) && e.Response != nil {
+		switch code := e.Response.StatusCode; {
+		case code == http.StatusTooManyRequests:
+			return ErrorClassThrottled
+		case code == http.StatusRequestTimeout,
+			code >= http.StatusInternalServerError && code != http.StatusNotImplemented:
+			return ErrorClassTransient
+		case isQuotaMessage(e):
+			return ErrorClassQuota
+		}
+		return ErrorClassPermanent
+	}
+
+	var ne net.Error
+	if errors.As(err, &ne) || errors.Is(err, context.DeadlineExceeded) {
+		return ErrorClassTransient
+	}
+
+	return ErrorClassPermanent
+}
+
+// isQuotaMessage returns true if an error response reports that a limit on the
+// number of resources, rather than on the request rate, was reached.
+func isQuotaMessage(e *packngo.ErrorResponse) bool {
+	switch e.Response.StatusCode {
+	case http.StatusForbidden, http.StatusUnprocessableEntity, http.StatusPaymentRequired:
+	default:
+		return false
+	}
+	msg := strings.ToLower(strings.Join(append(e.Errors, e.SingleError), " "))
+	return strings.Contains(msg, "quota") || strings.Contains(msg, "limit")
+}
+
+// statusCodeOf returns the HTTP status code of the Equinix Metal API error
+// response wrapped by the supplied error, or zero.
+func statusCodeOf(err error) int {
+	var e *packngo.ErrorResponse
+	if errors.As(err, &e) && e.Response != nil {
+		return e.Response.StatusCode
+	}
+	return 0
+}
+
+// IsThrottled returns true if the error was caused by API rate limiting.
+func IsThrottled(err error) bool {
+	return ClassifyError(err) == ErrorClassThrottled
+}
+
+// IsTransient returns true if the error is a server or network failure that
+// is expected to resolve without intervention.
+func IsTransient(err error) bool {
+	return ClassifyError(err) == ErrorClassTransient
+}
+
+// IsQuotaExceeded returns true if the error was caused by an organization or
+// project resource limit.
+func IsQuotaExceeded(err error) bool {
+	return ClassifyError(err) == ErrorClassQuota
+}
+
+// IsPermanent returns true if the error will recur until the request changes.
+func IsPermanent(err error) bool {
+	return ClassifyError(err) == ErrorClassPermanent
+}
+
+// RequeueAfter returns how long a reconcile that failed with the supplied
+// error should wait before trying again. Throttled errors honor the
+// Retry-After and X-RateLimit-Reset headers of the API response. Zero is
+// returned for errors that should use the default controller backoff.
+func RequeueAfter(err error) time.Duration {
+	switch ClassifyError(err) {
+	case ErrorClassThrottled:
+		var e *packngo.ErrorResponse
+		if errors.As(err, &e) {
+			if d, ok := retryAfter(e.Response); ok {
+				return d
+			}
+			if reset, ok := resetTime(e.Response); ok {
+				return time.Until(reset)
+			}
+		}
+		return throttledRequeueAfter
+	case ErrorClassTransient:
+		return transientRequeueAfter
+	case ErrorClassQuota:
+		return quotaRequeueAfter
+	case ErrorClassNone, ErrorClassPermanent:
+	}
+	return 0
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clients
+
+import (
+	"github.com/packethost/packngo"
+)
+
+// FacilityAny is the facility value that lets the Equinix Metal API choose a
+// facility.
+const FacilityAny = "any"
+
+// MetroCode returns the code of the supplied metro, or of the metro of the
+// supplied facility if the metro is not known.
+func MetroCode(m *packngo.Metro, f *packngo.Facility) string {
+	switch {
+	case m != nil && m.Code != "":
+		return m.Code
+	case f != nil && f.Metro != nil:
+		return f.Metro.Code
+	}
+	return ""
+}
+
+// LocationCompatible returns true if the supplied facility and metro describe
+// the observed location of a resource. Either may be empty, so that a spec
+// migrating from facility to metro remains compatible with a resource created
+// in a facility of that metro.
+func LocationCompatible(facility, metro, observedFacility, observedMetro string) bool {
+	if facility != "" && facility != FacilityAny && observedFacility != "" && facility != observedFacility {
+		return false
+	}
+	if metro != "" && observedMetro != "" && metro != observedMetro {
+		return false
+	}
+	return true
+}
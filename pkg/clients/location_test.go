@@ -0,0 +1,67 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clients
+
+import (
+	"testing"
+)
+
+func TestLocationCompatible(t *testing.T) {
+	type args struct {
+		facility, metro                 string
+		observedFacility, observedMetro string
+	}
+
+	cases := map[string]struct {
+		args args
+		want bool
+	}{
+		"SameFacility": {
+			args: args{facility: "sv15", observedFacility: "sv15", observedMetro: "sv"},
+			want: true,
+		},
+		"FacilityMigratedToMetro": {
+			args: args{metro: "sv", observedFacility: "sv15", observedMetro: "sv"},
+			want: true,
+		},
+		"FacilityWithLateInitializedMetro": {
+			args: args{facility: "sv15", metro: "sv", observedFacility: "sv15", observedMetro: "sv"},
+			want: true,
+		},
+		"AnyFacility": {
+			args: args{facility: FacilityAny, observedFacility: "sv15", observedMetro: "sv"},
+			want: true,
+		},
+		"DifferentFacility": {
+			args: args{facility: "da11", observedFacility: "sv15", observedMetro: "sv"},
+			want: false,
+		},
+		"DifferentMetro": {
+			args: args{metro: "da", observedFacility: "sv15", observedMetro: "sv"},
+			want: false,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			a := tc.args
+			if got := LocationCompatible(a.facility, a.metro, a.observedFacility, a.observedMetro); got != tc.want {
+				t.Errorf("LocationCompatible(...): want %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
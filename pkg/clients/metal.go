@@ -30,6 +30,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/packethost/crossplane-provider-equinix-metal/apis/v1beta1"
+	"github.com/packethost/crossplane-provider-equinix-metal/pkg/tracing"
 	"github.com/packethost/crossplane-provider-equinix-metal/pkg/version"
 )
 
@@ -46,6 +47,9 @@ type Client struct {
 const (
 	errVirtualNetworkAlreadyContents = " already "
 	errVirtualNetworkAlreadyPrefix   = "Virtual network"
+
+	errParseCredentials = "cannot parse credentials as a JSON object with an apiKey field or as a plain API token"
+	errNoAPIKey         = "no apiKey found in credentials"
 )
 
 // NewCredentialsFromJSON parses JSON bytes returning an Equinix Metal Credentials configuration
@@ -57,23 +61,54 @@ func NewCredentialsFromJSON(j []byte) (*Credentials, error) {
 	return config, nil
 }
 
+// ParseCredentials parses credentials that are either a JSON object, as
+// parsed by NewCredentialsFromJSON, or a plain API token.
+func ParseCredentials(data []byte) (*Credentials, error) {
+	s := strings.TrimSpace(string(data))
+	if strings.HasPrefix(s, "{") {
+		config, err := NewCredentialsFromJSON([]byte(s))
+		return config, errors.Wrap(err, errParseCredentials)
+	}
+	if strings.ContainsAny(s, " \t\r\n{}[]\"'") {
+		return nil, errors.New(errParseCredentials)
+	}
+	return &Credentials{APIKey: s}, nil
+}
+
 // NewClient returns an Equinix Metal Client configured with credentials
 func NewClient(ctx context.Context, config *Credentials) (*Client, error) {
 	apiKey := config.GetAPIKey(CredentialAPIKey)
 	if apiKey == "" {
-		return nil, fmt.Errorf("Invalid APIKey in credentials")
+		return nil, errors.New(errNoAPIKey)
 	}
-	apiClient := packngo.NewClientWithAuth("crossplane", apiKey, nil)
-	apiClient.UserAgent = fmt.Sprintf("crossplane-provider-equinix-metal/%s %s", version.Version, apiClient.UserAgent)
 
 	client := &Client{
-		Client:      apiClient,
+		Client:      newAPIClient(apiKey, SharedTransport()),
 		Credentials: config,
 	}
 
 	return client, nil
 }
 
+// WithScope returns a copy of the Client whose API requests are traced as
+// children of the current span of the supplied Scope. It returns the Client
+// itself if the Scope is nil.
+func (c *Client) WithScope(s *tracing.Scope) *Client {
+	if s == nil {
+		return c
+	}
+	return &Client{
+		Client:      newAPIClient(c.GetAPIKey(CredentialAPIKey), s.Transport(SharedTransport())),
+		Credentials: c.Credentials,
+	}
+}
+
+func newAPIClient(apiKey string, rt http.RoundTripper) *packngo.Client {
+	apiClient := packngo.NewClientWithAuth("crossplane", apiKey, &http.Client{Transport: rt})
+	apiClient.UserAgent = fmt.Sprintf("crossplane-provider-equinix-metal/%s %s", version.Version, apiClient.UserAgent)
+	return apiClient
+}
+
 // GetAuthInfo returns the necessary authentication information that is
 // necessary to use when the controller connects to Equinix Metal API in order
 // to reconcile the managed resource.
@@ -98,18 +133,8 @@ func UseProviderConfig(ctx context.Context, c client.Client, mg resource.Managed
 	if err := c.Get(ctx, types.NamespacedName{Name: mg.GetProviderConfigReference().Name}, pc); err != nil {
 		return nil, err
 	}
-	data, err := resource.CommonCredentialExtractor(ctx, pc.Spec.Credentials.Source, c, pc.Spec.Credentials.CommonCredentialSelectors)
-	if err != nil {
-		return nil, errors.Wrap(err, "cannot get credentials")
-	}
-	config, err := NewCredentialsFromJSON(data)
-	if err != nil {
-		return nil, err
-	}
-	if pc.Spec.ProjectID != "" {
-		config.SetProjectID(pc.Spec.ProjectID)
-	}
-	return config, err
+	config, _, err := loadCredentials(ctx, c, pc)
+	return config, errors.Wrap(err, errGetCredentials)
 }
 
 // IsNotFound returns true if error is not found
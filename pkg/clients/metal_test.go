@@ -0,0 +1,70 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clients
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestParseCredentials(t *testing.T) {
+	type want struct {
+		creds *Credentials
+		err   error
+	}
+
+	cases := map[string]struct {
+		data string
+		want want
+	}{
+		"JSON": {
+			data: `{"apiKey":"token","projectID":"project","facilityID":"ewr1"}`,
+			want: want{creds: &Credentials{APIKey: "token", ProjectID: "project", FacilityID: "ewr1"}},
+		},
+		"PlainToken": {
+			data: "token\n",
+			want: want{creds: &Credentials{APIKey: "token"}},
+		},
+		"Empty": {
+			want: want{creds: &Credentials{}},
+		},
+		"InvalidJSON": {
+			data: `{"apiKey":`,
+			want: want{err: errors.Wrap(errors.New("unexpected end of JSON input"), errParseCredentials)},
+		},
+		"Unparseable": {
+			data: "apiKey: token",
+			want: want{err: errors.New(errParseCredentials)},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseCredentials([]byte(tc.data))
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("ParseCredentials(...): -want error, +got error:\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.want.creds, got); diff != "" {
+				t.Errorf("ParseCredentials(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
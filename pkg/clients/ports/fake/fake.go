@@ -32,6 +32,7 @@ type MockClient struct {
 
 	MockGetProjectID  func(string) string
 	MockGetFacilityID func(string) string
+	MockGetMetro      func(string) string
 }
 
 // Assign calls the MockClient's MockAssign function.
@@ -54,6 +55,11 @@ func (c *MockClient) GetFacilityID(id string) string {
 	return c.MockGetFacilityID(id)
 }
 
+// GetMetro calls the MockClient's MockGetMetro function.
+func (c *MockClient) GetMetro(metro string) string {
+	return c.MockGetMetro(metro)
+}
+
 // GetProjectID calls the MockClient's MockGet function.
 func (c *MockClient) GetProjectID(id string) string {
 	return c.MockGetProjectID(id)
@@ -57,10 +57,14 @@ func NewClient(ctx context.Context, config *clients.Credentials) (ClientWithDefa
 	if err != nil {
 		return nil, err
 	}
-	portsClient := CredentialedClient{
+	return NewClientFromAPI(ctx, client)
+}
+
+// NewClientFromAPI returns a Client implementing the Equinix Metal API methods
+// needed to interact with Ports, using an existing Equinix Metal API client
+func NewClientFromAPI(_ context.Context, client *clients.Client) (ClientWithDefaults, error) {
+	return CredentialedClient{
 		Client:      client.Client.DevicePorts, //nolint:staticcheck
 		Credentials: client.Credentials,
-	}
-	portsClient.SetProjectID(config.ProjectID)
-	return portsClient, nil
+	}, nil
 }
@@ -0,0 +1,164 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clients
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/ratelimiter"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/packethost/crossplane-provider-equinix-metal/pkg/metrics"
+)
+
+// A RateLimiter is a controller rate limiter that delays the requeue of
+// managed resources whose last Equinix Metal API call failed with a throttled,
+// transient or quota error until the API is expected to accept the call.
+// Other requeues are delegated to the wrapped rate limiter.
+type RateLimiter struct {
+	ratelimiter.RateLimiter
+
+	mu        sync.Mutex
+	notBefore map[interface{}]time.Time
+}
+
+// NewRateLimiter returns a RateLimiter wrapping the supplied rate limiter. The
+// controller-runtime default rate limiter is used when rl is nil.
+func NewRateLimiter(rl ratelimiter.RateLimiter) *RateLimiter {
+	if rl == nil {
+		rl = workqueue.DefaultControllerRateLimiter()
+	}
+	return &RateLimiter{RateLimiter: rl, notBefore: map[interface{}]time.Time{}}
+}
+
+// When returns the longer of the delay requested for the item by a classified
+// error and the delay of the wrapped rate limiter.
+func (r *RateLimiter) When(item interface{}) time.Duration {
+	d := r.RateLimiter.When(item)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	nb, ok := r.notBefore[item]
+	if !ok {
+		return d
+	}
+	delete(r.notBefore, item)
+	if until := time.Until(nb); until > d {
+		return until
+	}
+	return d
+}
+
+// Forget the item's requested delay and its history in the wrapped rate
+// limiter.
+func (r *RateLimiter) Forget(item interface{}) {
+	r.mu.Lock()
+	delete(r.notBefore, item)
+	r.mu.Unlock()
+	r.RateLimiter.Forget(item)
+}
+
+// requeueAfter records that the supplied managed resource should not be
+// reconciled again until d has elapsed.
+func (r *RateLimiter) requeueAfter(mg resource.Object, d time.Duration) {
+	if r == nil || d <= 0 {
+		return
+	}
+	item := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: mg.GetNamespace(), Name: mg.GetName()}}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notBefore[item] = time.Now().Add(d)
+}
+
+// classifyingExternal is a managed.ExternalClient that classifies and counts
+// the errors returned by the ExternalClient it wraps.
+type classifyingExternal struct {
+	managed.ExternalClient
+	limiter *RateLimiter
+	reasons []string
+}
+
+// NewClassifyingExternalClient wraps the supplied ExternalClient such that its
+// throttled, transient and quota errors describe how they will be retried and
+// delay the next reconcile of the managed resource through the supplied
+// RateLimiter, which may be nil. Errors are counted by the supplied error
+// message that describes them, which are the error message constants of the
+// controller.
+func NewClassifyingExternalClient(e managed.ExternalClient, rl *RateLimiter, reasons ...string) managed.ExternalClient {
+	return &classifyingExternal{ExternalClient: e, limiter: rl, reasons: reasons}
+}
+
+func (c *classifyingExternal) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	o, err := c.ExternalClient.Observe(ctx, mg)
+	return o, c.classify(mg, err)
+}
+
+func (c *classifyingExternal) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	cr, err := c.ExternalClient.Create(ctx, mg)
+	return cr, c.classify(mg, err)
+}
+
+func (c *classifyingExternal) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	u, err := c.ExternalClient.Update(ctx, mg)
+	return u, c.classify(mg, err)
+}
+
+func (c *classifyingExternal) Delete(ctx context.Context, mg resource.Managed) error {
+	return c.classify(mg, c.ExternalClient.Delete(ctx, mg))
+}
+
+func (c *classifyingExternal) classify(mg resource.Managed, err error) error {
+	if err != nil {
+		metrics.RecordReconcileError(reflect.TypeOf(mg).Elem().Name(), errorReason(err, c.reasons), string(ClassifyError(err)))
+	}
+	class := ClassifyError(err)
+	if class == ErrorClassNone || class == ErrorClassPermanent {
+		return err
+	}
+	d := RequeueAfter(err)
+	c.limiter.requeueAfter(mg, d)
+	return errors.Wrap(err, fmt.Sprintf("%s Equinix Metal API error, retrying in %s", class, d.Round(time.Second)))
+}
+
+// errorReason returns the supplied error message that describes the supplied
+// error, or metrics.ReasonOther if none does. An error is described by the
+// message it was wrapped with last, or, for a format, by the message up to its
+// first verb. The longest such message is returned.
+func errorReason(err error, reasons []string) string {
+	msg, reason, longest := err.Error(), metrics.ReasonOther, 0
+	for _, r := range reasons {
+		prefix := r
+		if i := strings.IndexByte(r, '%'); i >= 0 {
+			prefix = r[:i]
+		}
+		if len(prefix) > longest && strings.HasPrefix(msg, prefix) {
+			reason, longest = r, len(prefix)
+		}
+	}
+	return reason
+}
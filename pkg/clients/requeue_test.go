@@ -0,0 +1,70 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clients
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/packethost/packngo"
+	"github.com/pkg/errors"
+
+	"github.com/packethost/crossplane-provider-equinix-metal/pkg/metrics"
+)
+
+func TestErrorReason(t *testing.T) {
+	const (
+		errGetDevice        = "cannot get Device"
+		errGetDeviceIPs     = "cannot get Device IP addresses"
+		errConvertStepFmt   = "cannot convert Device network type at step %s"
+		errAssignAddressFmt = "cannot assign IP address %s to Device"
+	)
+	reasons := []string{errGetDevice, errGetDeviceIPs, errConvertStepFmt, errAssignAddressFmt}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.equinix.com/metal/v1/devices/8f7b3c", nil)
+	errResponse := &packngo.ErrorResponse{Response: &http.Response{StatusCode: http.StatusNotFound, Request: req}, SingleError: "Not found"}
+
+	cases := map[string]struct {
+		err  error
+		want string
+	}{
+		"Message": {
+			err:  errors.Wrap(errResponse, errGetDevice),
+			want: errGetDevice,
+		},
+		"LongestMessage": {
+			err:  errors.Wrap(errResponse, errGetDeviceIPs),
+			want: errGetDeviceIPs,
+		},
+		"Format": {
+			err:  errors.Wrapf(errResponse, errAssignAddressFmt, "192.0.2.1"),
+			want: errAssignAddressFmt,
+		},
+		"Other": {
+			err:  errors.Wrap(errors.New("boom"), "cannot render userdata for Device my-device"),
+			want: metrics.ReasonOther,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, errorReason(tc.err, reasons)); diff != "" {
+				t.Errorf("errorReason(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
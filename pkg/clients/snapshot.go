@@ -0,0 +1,263 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clients
+
+import (
+	"context"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/packethost/packngo"
+)
+
+const (
+	// snapshotPageSize is the number of devices requested per page when
+	// listing the devices of a project.
+	snapshotPageSize = 100
+
+	// Projects that have not been read for this many intervals are no longer
+	// polled, and snapshots that have not been refreshed for this many
+	// intervals are no longer served.
+	snapshotExpiryIntervals = 3
+)
+
+// A Snapshotter periodically lists the Devices and VirtualNetworks of every
+// project that managed resources are observed in, so that observing hundreds
+// of resources costs a few list calls per interval rather than one call per
+// resource per reconcile.
+type Snapshotter struct {
+	interval time.Duration
+	log      logging.Logger
+
+	mu       sync.Mutex
+	projects map[projectKey]*ProjectSnapshot
+	wake     chan struct{}
+}
+
+type projectKey struct {
+	token     [sha256.Size]byte
+	projectID string
+}
+
+// NewSnapshotter returns a Snapshotter that refreshes project snapshots at the
+// supplied interval once started.
+func NewSnapshotter(interval time.Duration, l logging.Logger) *Snapshotter {
+	return &Snapshotter{
+		interval: interval,
+		log:      l,
+		projects: map[projectKey]*ProjectSnapshot{},
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Project returns the snapshot of the supplied project, listed using the
+// supplied client. Projects are polled from their first use. A nil Snapshotter
+// returns a nil ProjectSnapshot, which contains no resources.
+func (s *Snapshotter) Project(c *Client, projectID string) *ProjectSnapshot {
+	if s == nil || c == nil || projectID == "" {
+		return nil
+	}
+	k := projectKey{token: sha256.Sum256([]byte(c.GetAPIKey(CredentialAPIKey))), projectID: projectID}
+
+	s.mu.Lock()
+	p, ok := s.projects[k]
+	if !ok {
+		p = &ProjectSnapshot{projectID: projectID, maxAge: snapshotExpiryIntervals * s.interval}
+		s.projects[k] = p
+	}
+	s.mu.Unlock()
+
+	p.use(c)
+	if !ok {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+	return p
+}
+
+// Start polling projects until the supplied context is done.
+func (s *Snapshotter) Start(ctx context.Context) error {
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		case <-s.wake:
+		}
+		s.refresh(ctx)
+	}
+}
+
+func (s *Snapshotter) refresh(ctx context.Context) {
+	expiry := time.Now().Add(-snapshotExpiryIntervals * s.interval)
+
+	s.mu.Lock()
+	projects := make([]*ProjectSnapshot, 0, len(s.projects))
+	for k, p := range s.projects {
+		if p.lastUsed().Before(expiry) {
+			delete(s.projects, k)
+			continue
+		}
+		projects = append(projects, p)
+	}
+	s.mu.Unlock()
+
+	for _, p := range projects {
+		if ctx.Err() != nil {
+			return
+		}
+		if !p.refreshedAt().Before(time.Now().Add(-s.interval / 2)) {
+			continue
+		}
+		if err := p.refresh(); err != nil {
+			s.log.Debug("Cannot refresh project snapshot", "project", p.projectID, "error", err)
+		}
+	}
+}
+
+// A ProjectSnapshot is a periodically refreshed listing of the Devices and
+// VirtualNetworks of an Equinix Metal project.
+type ProjectSnapshot struct {
+	projectID string
+	maxAge    time.Duration
+
+	mu        sync.RWMutex
+	client    *Client
+	used      time.Time
+	refreshed time.Time
+	devices   map[string]packngo.Device
+	vlans     map[string]packngo.VirtualNetwork
+	forgotten map[string]time.Time
+}
+
+func (p *ProjectSnapshot) use(c *Client) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.client = c
+	p.used = time.Now()
+}
+
+func (p *ProjectSnapshot) lastUsed() time.Time {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.used
+}
+
+func (p *ProjectSnapshot) refreshedAt() time.Time {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.refreshed
+}
+
+func (p *ProjectSnapshot) refresh() error {
+	p.mu.RLock()
+	c := p.client
+	p.mu.RUnlock()
+
+	start := time.Now()
+	devices, _, err := c.Client.Devices.List(p.projectID, &packngo.ListOptions{PerPage: snapshotPageSize})
+	if err != nil {
+		return err
+	}
+	vlans, _, err := c.Client.ProjectVirtualNetworks.List(p.projectID, nil)
+	if err != nil {
+		return err
+	}
+
+	dm := make(map[string]packngo.Device, len(devices))
+	for _, d := range devices {
+		dm[d.ID] = d
+	}
+	vm := make(map[string]packngo.VirtualNetwork, len(vlans.VirtualNetworks))
+	for _, v := range vlans.VirtualNetworks {
+		vm[v.ID] = v
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.devices, p.vlans = dm, vm
+	p.refreshed = start
+	for id, t := range p.forgotten {
+		if t.Before(start) {
+			delete(p.forgotten, id)
+		}
+	}
+	return nil
+}
+
+// fresh returns true if the snapshot has been listed recently enough to be
+// trusted, and was listed after the supplied resource was last changed. The
+// caller must hold the read lock.
+func (p *ProjectSnapshot) fresh(id string) bool {
+	if p.refreshed.IsZero() || time.Since(p.refreshed) >= p.maxAge {
+		return false
+	}
+	t, ok := p.forgotten[id]
+	return !ok || t.Before(p.refreshed)
+}
+
+// Device returns a copy of the snapshotted Device with the supplied ID. It
+// returns false if the snapshot is stale or does not include the Device.
+func (p *ProjectSnapshot) Device(id string) (*packngo.Device, bool) {
+	if p == nil {
+		return nil, false
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if !p.fresh(id) {
+		return nil, false
+	}
+	d, ok := p.devices[id]
+	return &d, ok
+}
+
+// VirtualNetwork returns a copy of the snapshotted VirtualNetwork with the
+// supplied ID. It returns false if the snapshot is stale or does not include
+// the VirtualNetwork.
+func (p *ProjectSnapshot) VirtualNetwork(id string) (*packngo.VirtualNetwork, bool) {
+	if p == nil {
+		return nil, false
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if !p.fresh(id) {
+		return nil, false
+	}
+	v, ok := p.vlans[id]
+	return &v, ok
+}
+
+// Forget the resource with the supplied ID until the snapshot is next listed.
+// It is called after the resource is changed through the API so that the next
+// observation reads the change rather than the snapshot.
+func (p *ProjectSnapshot) Forget(id string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.forgotten == nil {
+		p.forgotten = map[string]time.Time{}
+	}
+	p.forgotten[id] = time.Now()
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clients
+
+import (
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ManagedTagPrefix prefixes the tags the provider adds to the Equinix Metal
+// resources it manages. Tags with this prefix are owned by the provider.
+const ManagedTagPrefix = "crossplane.io/"
+
+// Labels Crossplane adds to the managed resources of a composite resource.
+const (
+	labelKeyClaimName      = "crossplane.io/claim-name"
+	labelKeyClaimNamespace = "crossplane.io/claim-namespace"
+	labelKeyComposite      = "crossplane.io/composite"
+)
+
+// ManagedTags returns the tags that trace an Equinix Metal resource back to
+// the supplied managed resource, and to the claim and composite resource it
+// belongs to, if any.
+func ManagedTags(o client.Object) []string {
+	tags := []string{
+		ManagedTagPrefix + "name=" + o.GetName(),
+		ManagedTagPrefix + "uid=" + string(o.GetUID()),
+	}
+	l := o.GetLabels()
+	if n := l[labelKeyClaimName]; n != "" {
+		tags = append(tags, ManagedTagPrefix+"claim="+l[labelKeyClaimNamespace]+"/"+n)
+	}
+	if c := l[labelKeyComposite]; c != "" {
+		tags = append(tags, ManagedTagPrefix+"composite="+c)
+	}
+	return tags
+}
+
+// IsManagedTag returns true if the supplied tag is owned by the provider.
+func IsManagedTag(tag string) bool {
+	return strings.HasPrefix(tag, ManagedTagPrefix)
+}
+
+// MergeTags returns the supplied user tags, without any that are owned by the
+// provider, followed by the supplied managed tags. Duplicates are removed.
+func MergeTags(user, managed []string) []string {
+	seen := make(map[string]bool, len(user)+len(managed))
+	tags := make([]string, 0, len(user)+len(managed))
+	for _, t := range user {
+		if IsManagedTag(t) || seen[t] {
+			continue
+		}
+		seen[t] = true
+		tags = append(tags, t)
+	}
+	for _, t := range managed {
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		tags = append(tags, t)
+	}
+	return tags
+}
+
+// UserTags returns the supplied tags without those owned by the provider, or
+// nil if there are none.
+func UserTags(tags []string) []string {
+	user := MergeTags(tags, nil)
+	if len(user) == 0 {
+		return nil
+	}
+	return user
+}
+
+// TagsEqual returns true if the supplied tags are the same set of tags,
+// regardless of their order.
+func TagsEqual(a, b []string) bool {
+	sa, sb := uniqueSorted(a), uniqueSorted(b)
+	if len(sa) != len(sb) {
+		return false
+	}
+	for i := range sa {
+		if sa[i] != sb[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func uniqueSorted(tags []string) []string {
+	s := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		if !seen[t] {
+			seen[t] = true
+			s = append(s, t)
+		}
+	}
+	sort.Strings(s)
+	return s
+}
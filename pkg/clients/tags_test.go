@@ -0,0 +1,101 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clients
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestManagedTags(t *testing.T) {
+	cases := map[string]struct {
+		o    *metav1.PartialObjectMetadata
+		want []string
+	}{
+		"Unowned": {
+			o:    &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: "cool", UID: "uid"}},
+			want: []string{"crossplane.io/name=cool", "crossplane.io/uid=uid"},
+		},
+		"Claimed": {
+			o: &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: "cool-xyz", UID: "uid", Labels: map[string]string{
+				labelKeyClaimNamespace: "default",
+				labelKeyClaimName:      "cool",
+				labelKeyComposite:      "cool-abc",
+			}}},
+			want: []string{"crossplane.io/name=cool-xyz", "crossplane.io/uid=uid", "crossplane.io/claim=default/cool", "crossplane.io/composite=cool-abc"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := ManagedTags(tc.o)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ManagedTags(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMergeTags(t *testing.T) {
+	cases := map[string]struct {
+		user    []string
+		managed []string
+		want    []string
+	}{
+		"Merged": {
+			user:    []string{"a", "b", "a"},
+			managed: []string{"crossplane.io/name=cool", "b"},
+			want:    []string{"a", "b", "crossplane.io/name=cool"},
+		},
+		"StaleManagedTagsDropped": {
+			user:    []string{"a", "crossplane.io/name=old"},
+			managed: []string{"crossplane.io/name=cool"},
+			want:    []string{"a", "crossplane.io/name=cool"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := MergeTags(tc.user, tc.managed)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("MergeTags(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTagsEqual(t *testing.T) {
+	cases := map[string]struct {
+		a, b []string
+		want bool
+	}{
+		"Reordered": {a: []string{"a", "b"}, b: []string{"b", "a"}, want: true},
+		"Empty":     {a: nil, b: []string{}, want: true},
+		"Different": {a: []string{"a", "b"}, b: []string{"a", "c"}, want: false},
+		"Missing":   {a: []string{"a", "b"}, b: []string{"a"}, want: false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := TagsEqual(tc.a, tc.b); got != tc.want {
+				t.Errorf("TagsEqual(...): want %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,347 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clients
+
+import (
+	"crypto/sha256"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
+
+	"github.com/packethost/crossplane-provider-equinix-metal/pkg/metrics"
+)
+
+const (
+	headerAuthToken  = "X-Auth-Token"
+	headerRetryAfter = "Retry-After"
+	headerRateReset  = "X-RateLimit-Reset"
+	headerRateRemain = "X-RateLimit-Remaining"
+
+	// DefaultRequestsPerSecond is the default average rate of Equinix Metal
+	// API requests allowed per API token.
+	DefaultRequestsPerSecond = 5
+
+	// DefaultMaxRetries is the default number of times a request is retried
+	// before its error is returned to the caller.
+	DefaultMaxRetries = 4
+
+	defaultMinBackoff = 500 * time.Millisecond
+	defaultMaxBackoff = 30 * time.Second
+)
+
+// TransportOptions configure the rate limiting and retry behavior of a
+// Transport.
+type TransportOptions struct {
+	// RequestsPerSecond is the average rate of requests allowed per API token.
+	RequestsPerSecond float64
+
+	// Burst is the number of requests that may exceed RequestsPerSecond at
+	// once. It defaults to twice RequestsPerSecond.
+	Burst int
+
+	// MaxRetries is the number of times a throttled or transiently failed
+	// request is retried.
+	MaxRetries int
+
+	// MinBackoff and MaxBackoff bound the jittered exponential backoff
+	// between retries that are not directed by a Retry-After header.
+	// MaxBackoff also bounds every other wait of a request; throttled
+	// requests asked to wait longer are returned rather than retried.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// Transport is an http.RoundTripper that rate limits requests per API token,
+// honors the Retry-After and X-RateLimit-* headers returned by the Equinix
+// Metal API, and retries throttled requests, and idempotent requests that
+// failed with a server error, with jittered exponential backoff.
+type Transport struct {
+	Base http.RoundTripper
+
+	opts TransportOptions
+
+	mu       sync.Mutex
+	limiters map[[sha256.Size]byte]*tokenLimiter
+}
+
+// tokenLimiter tracks the request budget of a single API token.
+type tokenLimiter struct {
+	*rate.Limiter
+
+	mu      sync.Mutex
+	blocked time.Time
+}
+
+// NewTransport returns a Transport wrapping the supplied base RoundTripper.
+// http.DefaultTransport is used when base is nil.
+func NewTransport(base http.RoundTripper, o TransportOptions) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if o.RequestsPerSecond <= 0 {
+		o.RequestsPerSecond = DefaultRequestsPerSecond
+	}
+	if o.Burst <= 0 {
+		o.Burst = int(2 * o.RequestsPerSecond)
+	}
+	if o.MaxRetries < 0 {
+		o.MaxRetries = 0
+	}
+	if o.MinBackoff <= 0 {
+		o.MinBackoff = defaultMinBackoff
+	}
+	if o.MaxBackoff < o.MinBackoff {
+		o.MaxBackoff = defaultMaxBackoff
+	}
+	return &Transport{Base: base, opts: o, limiters: map[[sha256.Size]byte]*tokenLimiter{}}
+}
+
+var (
+	sharedTransportMu sync.Mutex
+	sharedTransport   = NewTransport(nil, TransportOptions{MaxRetries: DefaultMaxRetries})
+)
+
+// ConfigureTransport replaces the Transport shared by all Equinix Metal API
+// clients created by NewClient. It is intended to be called once at startup.
+func ConfigureTransport(o TransportOptions) {
+	sharedTransportMu.Lock()
+	defer sharedTransportMu.Unlock()
+	sharedTransport = NewTransport(nil, o)
+}
+
+// SharedTransport returns the Transport shared by all Equinix Metal API
+// clients created by NewClient.
+func SharedTransport() *Transport {
+	sharedTransportMu.Lock()
+	defer sharedTransportMu.Unlock()
+	return sharedTransport
+}
+
+func (t *Transport) limiterFor(req *http.Request) *tokenLimiter {
+	key := sha256.Sum256([]byte(req.Header.Get(headerAuthToken)))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	l, ok := t.limiters[key]
+	if !ok {
+		l = &tokenLimiter{Limiter: rate.NewLimiter(rate.Limit(t.opts.RequestsPerSecond), t.opts.Burst)}
+		t.limiters[key] = l
+	}
+	return l
+}
+
+// block prevents any request with this token from being sent before until.
+func (l *tokenLimiter) block(until time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until.After(l.blocked) {
+		l.blocked = until
+	}
+}
+
+func (l *tokenLimiter) blockedUntil() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.blocked
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) { //nolint:gocyclo
+	ctx := req.Context()
+	l := t.limiterFor(req)
+
+	for attempt := 0; ; attempt++ {
+		if err := sleep(req, time.Until(l.blockedUntil())); err != nil {
+			return nil, err
+		}
+		if err := l.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		r, err := rewind(req, attempt)
+		if err != nil {
+			return nil, err
+		}
+
+		start := time.Now()
+		resp, err := t.Base.RoundTrip(r)
+		metrics.ObserveAPIRequest(r, statusCode(resp), time.Since(start))
+		if reset, ok := rateLimitReset(resp); ok {
+			l.block(earliest(reset, time.Now().Add(t.opts.MaxBackoff)))
+		}
+		if attempt >= t.opts.MaxRetries || !shouldRetry(r, resp, err) {
+			return resp, err
+		}
+
+		wait := t.backoff(attempt)
+		if d, ok := retryAfter(resp); ok {
+			// Longer waits are left to the requeue of the reconcile, rather
+			// than stalling every request with the token.
+			if d > t.opts.MaxBackoff {
+				l.block(time.Now().Add(t.opts.MaxBackoff))
+				return resp, err
+			}
+			wait = d
+			l.block(time.Now().Add(d))
+		}
+		drain(resp)
+
+		trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(
+			attribute.Int("http.status_code", statusCode(resp)),
+			attribute.String("wait", wait.String()),
+		))
+		if err := sleep(req, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// backoff returns a full-jitter exponential backoff for the supplied attempt.
+func (t *Transport) backoff(attempt int) time.Duration {
+	d := t.opts.MinBackoff << uint(attempt)
+	if d <= 0 || d > t.opts.MaxBackoff {
+		d = t.opts.MaxBackoff
+	}
+	return t.opts.MinBackoff/2 + time.Duration(rand.Int63n(int64(d))) // nolint:gosec
+}
+
+// shouldRetry returns true if the request may be safely sent again. Throttled
+// requests were never processed, so they are always retried. Transport errors
+// and server errors are only retried for idempotent methods.
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	if err != nil {
+		return isIdempotent(req.Method) && req.Context().Err() == nil
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true
+	case resp.StatusCode >= http.StatusInternalServerError && resp.StatusCode != http.StatusNotImplemented:
+		return isIdempotent(req.Method)
+	}
+	return false
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// rewind returns a request suitable for the supplied attempt, recreating the
+// body of requests that are being retried.
+func rewind(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 0 || req.GetBody == nil {
+		return req, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	r := req.Clone(req.Context())
+	r.Body = body
+	return r, nil
+}
+
+func statusCode(resp *http.Response) int {
+	if resp == nil {
+		return 0
+	}
+	return resp.StatusCode
+}
+
+func earliest(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func sleep(req *http.Request, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-req.Context().Done():
+		return req.Context().Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func drain(resp *http.Response) {
+	if resp == nil || resp.Body == nil {
+		return
+	}
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096))
+	_ = resp.Body.Close()
+}
+
+// retryAfter returns the delay requested by the Retry-After header of the
+// supplied response, which may be expressed in seconds or as an HTTP date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	v := resp.Header.Get(headerRetryAfter)
+	if v == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(v); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t), true
+	}
+	return 0, false
+}
+
+// rateLimitReset returns the time at which the request budget resets when the
+// supplied response reports that the budget has been exhausted.
+func rateLimitReset(resp *http.Response) (time.Time, bool) {
+	if resp == nil || resp.Header.Get(headerRateRemain) != "0" {
+		return time.Time{}, false
+	}
+	return resetTime(resp)
+}
+
+// resetTime returns the time reported by the X-RateLimit-Reset header of the
+// supplied response, expressed in seconds since the epoch.
+func resetTime(resp *http.Response) (time.Time, bool) {
+	if resp == nil {
+		return time.Time{}, false
+	}
+	reset, err := strconv.ParseInt(resp.Header.Get(headerRateReset), 10, 64)
+	if err != nil || reset <= 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(reset, 0), true
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clients
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/packethost/packngo"
+	"github.com/pkg/errors"
+)
+
+func TestTransportRoundTrip(t *testing.T) {
+	type want struct {
+		status   int
+		attempts int
+	}
+
+	cases := map[string]struct {
+		method    string
+		responses []int
+		header    http.Header
+		want      want
+	}{
+		"Success": {
+			method:    http.MethodGet,
+			responses: []int{http.StatusOK},
+			want:      want{status: http.StatusOK, attempts: 1},
+		},
+		"RetryThrottledPost": {
+			method:    http.MethodPost,
+			responses: []int{http.StatusTooManyRequests, http.StatusCreated},
+			header:    http.Header{headerRetryAfter: []string{"0"}},
+			want:      want{status: http.StatusCreated, attempts: 2},
+		},
+		"LongRetryAfterReturned": {
+			method:    http.MethodGet,
+			responses: []int{http.StatusTooManyRequests, http.StatusOK},
+			header:    http.Header{headerRetryAfter: []string{"60"}},
+			want:      want{status: http.StatusTooManyRequests, attempts: 1},
+		},
+		"RetryServerErrorGet": {
+			method:    http.MethodGet,
+			responses: []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK},
+			want:      want{status: http.StatusOK, attempts: 3},
+		},
+		"NoRetryServerErrorPost": {
+			method:    http.MethodPost,
+			responses: []int{http.StatusInternalServerError, http.StatusCreated},
+			want:      want{status: http.StatusInternalServerError, attempts: 1},
+		},
+		"NoRetryClientError": {
+			method:    http.MethodGet,
+			responses: []int{http.StatusNotFound, http.StatusOK},
+			want:      want{status: http.StatusNotFound, attempts: 1},
+		},
+		"RetriesExhausted": {
+			method:    http.MethodGet,
+			responses: []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusOK},
+			want:      want{status: http.StatusBadGateway, attempts: 3},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			attempts := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range tc.header {
+					w.Header()[k] = v
+				}
+				w.WriteHeader(tc.responses[attempts])
+				attempts++
+			}))
+			defer srv.Close()
+
+			tr := NewTransport(srv.Client().Transport, TransportOptions{
+				RequestsPerSecond: 1000,
+				MaxRetries:        2,
+				MinBackoff:        time.Millisecond,
+				MaxBackoff:        time.Millisecond,
+			})
+			req, _ := http.NewRequest(tc.method, srv.URL, strings.NewReader("{}"))
+			resp, err := tr.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("tr.RoundTrip(...): unexpected error: %v", err)
+			}
+			_ = resp.Body.Close()
+
+			got := want{status: resp.StatusCode, attempts: attempts}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("tr.RoundTrip(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	errResponse := func(code int, msg string) error {
+		return &packngo.ErrorResponse{Response: &http.Response{StatusCode: code}, SingleError: msg}
+	}
+
+	cases := map[string]struct {
+		err  error
+		want ErrorClass
+	}{
+		"Nil": {
+			want: ErrorClassNone,
+		},
+		"Throttled": {
+			err:  errors.Wrap(errResponse(http.StatusTooManyRequests, ""), "cannot get Device"),
+			want: ErrorClassThrottled,
+		},
+		"Transient": {
+			err:  errResponse(http.StatusServiceUnavailable, ""),
+			want: ErrorClassTransient,
+		},
+		"Quota": {
+			err:  errResponse(http.StatusForbidden, "You have reached your device quota"),
+			want: ErrorClassQuota,
+		},
+		"Permanent": {
+			err:  errResponse(http.StatusUnprocessableEntity, "Plan is not valid"),
+			want: ErrorClassPermanent,
+		},
+		"NotAnAPIError": {
+			err:  errors.New("boom"),
+			want: ErrorClassPermanent,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, ClassifyError(tc.err)); diff != "" {
+				t.Errorf("ClassifyError(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
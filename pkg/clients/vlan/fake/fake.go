@@ -33,6 +33,7 @@ type MockClient struct {
 
 	MockGetProjectID  func(string) string
 	MockGetFacilityID func(string) string
+	MockGetMetro      func(string) string
 }
 
 // List calls the MockClient's MockList function.
@@ -60,6 +61,11 @@ func (c *MockClient) GetFacilityID(id string) string {
 	return c.MockGetFacilityID(id)
 }
 
+// GetMetro calls the MockClient's MockGetMetro function.
+func (c *MockClient) GetMetro(metro string) string {
+	return c.MockGetMetro(metro)
+}
+
 // GetProjectID calls the MockClient's MockGet function.
 func (c *MockClient) GetProjectID(id string) string {
 	return c.MockGetProjectID(id)
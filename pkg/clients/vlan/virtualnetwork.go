@@ -64,23 +64,39 @@ func NewClient(ctx context.Context, config *clients.Credentials) (ClientWithDefa
 	if err != nil {
 		return nil, err
 	}
-	vlanClient := CredentialedClient{
+	return NewClientFromAPI(ctx, client)
+}
+
+// NewClientFromAPI returns a Client implementing the Equinix Metal API methods
+// needed to interact with VirtualNetworks, using an existing Equinix Metal API
+// client
+func NewClientFromAPI(_ context.Context, client *clients.Client) (ClientWithDefaults, error) {
+	return CredentialedClient{
 		Client:      client.Client.ProjectVirtualNetworks,
 		Credentials: client.Credentials,
-	}
-	vlanClient.SetProjectID(config.ProjectID)
-	return vlanClient, nil
+	}, nil
 }
 
-// CreateFromVirtualNetwork return packngo.VirtualNetworkCreateRequest created from Kubernetes
+// CreateFromVirtualNetwork return packngo.VirtualNetworkCreateRequest created
+// from Kubernetes. The VirtualNetwork is created in the supplied project unless
+// it specifies another.
 func CreateFromVirtualNetwork(d *v1alpha1.VirtualNetwork, projectID string) *packngo.VirtualNetworkCreateRequest {
-	return &packngo.VirtualNetworkCreateRequest{
+	if d.Spec.ForProvider.ProjectID != "" {
+		projectID = d.Spec.ForProvider.ProjectID
+	}
+	r := &packngo.VirtualNetworkCreateRequest{
 		Facility:    d.Spec.ForProvider.Facility,
-		Metro:       d.Spec.ForProvider.Metro,
 		Description: emptyIfNil(d.Spec.ForProvider.Description),
 		ProjectID:   projectID,
 		VXLAN:       d.Spec.ForProvider.VXLAN,
 	}
+
+	// A metro late-initialized for a VirtualNetwork created in a facility is
+	// omitted, as the API accepts only one of them.
+	if r.Facility == "" {
+		r.Metro = d.Spec.ForProvider.Metro
+	}
+	return r
 }
 
 func emptyIfNil(in *string) string {
@@ -96,8 +112,10 @@ func GenerateObservation(vlan *packngo.VirtualNetwork) (v1alpha1.VirtualNetworkO
 	observation := v1alpha1.VirtualNetworkObservation{
 		ID:           vlan.ID,
 		Href:         vlan.Href,
+		ProjectID:    clients.ProjectID(vlan.Project),
 		VXLAN:        vlan.VXLAN,
 		FacilityCode: vlan.FacilityCode,
+		Metro:        metroCode(vlan),
 	}
 
 	if !observation.CreatedAt.IsZero() {
@@ -117,6 +135,18 @@ func LateInitialize(in *v1alpha1.VirtualNetworkParameters, vlan *packngo.Virtual
 	}
 
 	in.Description = clients.LateInitializeStringPtr(in.Description, &vlan.Description)
+
+	// Metro is late-initialized so that VirtualNetworks created in a facility
+	// may migrate to specifying only their metro.
+	metro := metroCode(vlan)
+	in.Metro = clients.LateInitializeString(in.Metro, &metro)
+}
+
+func metroCode(vlan *packngo.VirtualNetwork) string {
+	if vlan.MetroCode != "" {
+		return vlan.MetroCode
+	}
+	return clients.MetroCode(vlan.Metro, vlan.Facility)
 }
 
 // IsUpToDate returns true if the supplied Kubernetes resource does not differ
@@ -124,7 +154,7 @@ func LateInitialize(in *v1alpha1.VirtualNetworkParameters, vlan *packngo.Virtual
 // modified in place without deleting and recreating the instance, which are
 // immutable.
 func IsUpToDate(d *v1alpha1.VirtualNetwork, p *packngo.VirtualNetwork) bool {
-	if !nilOrEqualStr(&d.Spec.ForProvider.Facility, p.FacilityCode) {
+	if !clients.LocationCompatible(d.Spec.ForProvider.Facility, d.Spec.ForProvider.Metro, p.FacilityCode, metroCode(p)) {
 		return false
 	}
 	if !nilOrEqualStr(d.Spec.ForProvider.Description, p.Description) {
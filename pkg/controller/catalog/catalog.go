@@ -0,0 +1,381 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package catalog populates the observe-only Metro, Plan and OperatingSystem
+// resources from the Equinix Metal API.
+package catalog
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/packethost/packngo"
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+
+	"github.com/packethost/crossplane-provider-equinix-metal/apis/catalog/v1alpha1"
+	"github.com/packethost/crossplane-provider-equinix-metal/apis/v1beta1"
+	"github.com/packethost/crossplane-provider-equinix-metal/pkg/clients"
+)
+
+// Error strings.
+const (
+	errGetProviderConfig = "cannot get ProviderConfig"
+	errNewClient         = "cannot create Equinix Metal client"
+	errListMetros        = "cannot list metros"
+	errListPlans         = "cannot list plans"
+	errListOS            = "cannot list operating systems"
+	errApply             = "cannot apply catalog resource"
+	errPrune             = "cannot delete catalog resources no longer offered"
+)
+
+// capacityAvailable is the value of the metro labels of a Plan offered in a
+// metro of unknown capacity.
+const capacityAvailable = "available"
+
+// Setup adds a Syncer that populates the catalog resources every interval,
+// using the credentials of the named ProviderConfig.
+func Setup(mgr ctrl.Manager, l logging.Logger, cc *clients.ClientCache, providerConfig string, interval time.Duration) error {
+	return mgr.Add(&Syncer{
+		kube:           mgr.GetClient(),
+		cache:          cc,
+		providerConfig: providerConfig,
+		interval:       interval,
+		log:            l.WithValues("controller", "catalog"),
+	})
+}
+
+// A Syncer populates the Metro, Plan and OperatingSystem resources from the
+// Equinix Metal API, and deletes those that are no longer offered.
+type Syncer struct {
+	kube           client.Client
+	cache          *clients.ClientCache
+	providerConfig string
+	interval       time.Duration
+	log            logging.Logger
+}
+
+// Start syncing the catalog until the supplied context is done.
+func (s *Syncer) Start(ctx context.Context) error {
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+	for {
+		if err := s.sync(ctx); err != nil {
+			s.log.Info("Cannot sync catalog", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+func (s *Syncer) sync(ctx context.Context) error {
+	pc := &v1beta1.ProviderConfig{}
+	if err := s.kube.Get(ctx, types.NamespacedName{Name: s.providerConfig}, pc); err != nil {
+		if kerrors.IsNotFound(err) {
+			s.log.Debug("Not syncing catalog until its ProviderConfig exists", "providerConfig", s.providerConfig)
+			return nil
+		}
+		return errors.Wrap(err, errGetProviderConfig)
+	}
+	cl, err := s.cache.ClientFor(ctx, s.kube, pc)
+	if err != nil {
+		return errors.Wrap(err, errNewClient)
+	}
+
+	metros, _, err := cl.Client.Metros.List(nil)
+	if err != nil {
+		return errors.Wrap(err, errListMetros)
+	}
+	plans, _, err := cl.Client.Plans.List(&packngo.ListOptions{Includes: []string{"available_in", "available_in_metros"}})
+	if err != nil {
+		return errors.Wrap(err, errListPlans)
+	}
+	oss, _, err := cl.Client.OperatingSystems.List()
+	if err != nil {
+		return errors.Wrap(err, errListOS)
+	}
+	// Plans are still listed when their capacity is not known.
+	capacity, _, err := cl.Client.CapacityService.ListMetros()
+	if err != nil {
+		s.log.Debug("Cannot list capacity", "error", err)
+	}
+
+	var want []client.Object
+	for _, m := range metros {
+		want = append(want, metroObject(m))
+	}
+	for _, p := range plans {
+		want = append(want, planObject(p, capacity))
+	}
+	for _, o := range oss {
+		want = append(want, operatingSystemObject(o))
+	}
+
+	names := map[string]bool{}
+	for _, o := range want {
+		if err := s.apply(ctx, o); err != nil {
+			return errors.Wrap(err, errApply)
+		}
+		names[key(o)] = true
+	}
+	return errors.Wrap(s.prune(ctx, names), errPrune)
+}
+
+// apply creates the supplied catalog resource, or updates its labels and
+// status if they differ from those of the existing resource.
+func (s *Syncer) apply(ctx context.Context, want client.Object) error {
+	got := want.DeepCopyObject().(client.Object)
+	err := s.kube.Get(ctx, types.NamespacedName{Name: want.GetName()}, got)
+	switch {
+	case kerrors.IsNotFound(err):
+		got = want.DeepCopyObject().(client.Object)
+		if err := s.kube.Create(ctx, got); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	case !cmp.Equal(want.GetLabels(), got.GetLabels(), cmpopts.EquateEmpty()):
+		u := want.DeepCopyObject().(client.Object)
+		u.SetResourceVersion(got.GetResourceVersion())
+		if err := s.kube.Update(ctx, u); err != nil {
+			return err
+		}
+		got.SetResourceVersion(u.GetResourceVersion())
+	}
+
+	if cmp.Equal(status(want), status(got), cmpopts.EquateEmpty()) {
+		return nil
+	}
+	u := want.DeepCopyObject().(client.Object)
+	u.SetResourceVersion(got.GetResourceVersion())
+	return s.kube.Status().Update(ctx, u)
+}
+
+// prune deletes the catalog resources the provider populated that are not
+// among the supplied names.
+func (s *Syncer) prune(ctx context.Context, names map[string]bool) error {
+	managed := client.MatchingLabels{v1alpha1.LabelManagedBy: v1alpha1.ManagedByValue}
+
+	ml, pl, ol := &v1alpha1.MetroList{}, &v1alpha1.PlanList{}, &v1alpha1.OperatingSystemList{}
+	var existing []client.Object
+	if err := s.kube.List(ctx, ml, managed); err != nil {
+		return err
+	}
+	for i := range ml.Items {
+		existing = append(existing, &ml.Items[i])
+	}
+	if err := s.kube.List(ctx, pl, managed); err != nil {
+		return err
+	}
+	for i := range pl.Items {
+		existing = append(existing, &pl.Items[i])
+	}
+	if err := s.kube.List(ctx, ol, managed); err != nil {
+		return err
+	}
+	for i := range ol.Items {
+		existing = append(existing, &ol.Items[i])
+	}
+
+	for _, o := range existing {
+		if names[key(o)] {
+			continue
+		}
+		if err := s.kube.Delete(ctx, o); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func key(o client.Object) string {
+	switch o.(type) {
+	case *v1alpha1.Metro:
+		return v1alpha1.MetroKind + "/" + o.GetName()
+	case *v1alpha1.Plan:
+		return v1alpha1.PlanKind + "/" + o.GetName()
+	default:
+		return v1alpha1.OperatingSystemKind + "/" + o.GetName()
+	}
+}
+
+func status(o client.Object) interface{} {
+	switch t := o.(type) {
+	case *v1alpha1.Metro:
+		return t.Status
+	case *v1alpha1.Plan:
+		return t.Status
+	case *v1alpha1.OperatingSystem:
+		return t.Status
+	}
+	return nil
+}
+
+func metroObject(m packngo.Metro) *v1alpha1.Metro {
+	o := &v1alpha1.Metro{}
+	o.SetName(objectName(m.Code))
+	o.SetLabels(map[string]string{
+		v1alpha1.LabelManagedBy: v1alpha1.ManagedByValue,
+		v1alpha1.LabelSlug:      labelValue(m.Code),
+		v1alpha1.LabelCountry:   labelValue(m.Country),
+	})
+	o.Status.AtProvider = v1alpha1.MetroObservation{
+		ID:      m.ID,
+		Code:    m.Code,
+		Name:    m.Name,
+		Country: m.Country,
+	}
+	return o
+}
+
+func planObject(p packngo.Plan, capacity *packngo.CapacityReport) *v1alpha1.Plan {
+	o := &v1alpha1.Plan{}
+	o.SetName(objectName(p.Slug))
+	labels := map[string]string{
+		v1alpha1.LabelManagedBy: v1alpha1.ManagedByValue,
+		v1alpha1.LabelSlug:      labelValue(p.Slug),
+		v1alpha1.LabelClass:     labelValue(p.Class),
+		v1alpha1.LabelLine:      labelValue(p.Line),
+		v1alpha1.LabelLegacy:    strconv.FormatBool(p.Legacy),
+	}
+
+	obs := v1alpha1.PlanObservation{
+		ID:              p.ID,
+		Slug:            p.Slug,
+		Name:            p.Name,
+		Description:     p.Description,
+		Line:            p.Line,
+		Class:           p.Class,
+		Legacy:          p.Legacy,
+		DeploymentTypes: p.DeploymentTypes,
+	}
+	for _, t := range p.DeploymentTypes {
+		labels[v1alpha1.LabelDeploymentTypePrefix+labelValue(t)] = "true"
+	}
+	if p.Pricing != nil {
+		obs.Pricing = v1alpha1.PlanPricing{Hour: price(p.Pricing.Hour), Month: price(p.Pricing.Month)}
+	}
+	if p.Specs != nil {
+		obs.Specs = planSpecs(p.Specs)
+	}
+	for _, m := range p.AvailableInMetros {
+		a := v1alpha1.PlanAvailability{Metro: m.Code}
+		if capacity != nil {
+			a.Capacity = (*capacity)[m.Code][p.Slug].Level
+		}
+		obs.Metros = append(obs.Metros, a)
+
+		level := a.Capacity
+		if level == "" {
+			level = capacityAvailable
+		}
+		labels[v1alpha1.LabelMetroPrefix+labelValue(m.Code)] = labelValue(level)
+	}
+	sort.Slice(obs.Metros, func(i, j int) bool { return obs.Metros[i].Metro < obs.Metros[j].Metro })
+	for _, f := range p.AvailableIn {
+		obs.Facilities = append(obs.Facilities, f.Code)
+	}
+	sort.Strings(obs.Facilities)
+
+	o.SetLabels(labels)
+	o.Status.AtProvider = obs
+	return o
+}
+
+func planSpecs(s *packngo.Specs) v1alpha1.PlanSpecs {
+	out := v1alpha1.PlanSpecs{}
+	for _, c := range s.Cpus {
+		out.CPUs = append(out.CPUs, v1alpha1.PlanComponent{Count: c.Count, Type: c.Type})
+	}
+	if s.Memory != nil {
+		out.Memory = s.Memory.Total
+	}
+	for _, d := range s.Drives {
+		out.Drives = append(out.Drives, v1alpha1.PlanDrive{Count: d.Count, Size: d.Size, Type: d.Type})
+	}
+	for _, n := range s.Nics {
+		out.NICs = append(out.NICs, v1alpha1.PlanComponent{Count: n.Count, Type: n.Type})
+	}
+	if s.Features != nil {
+		out.RAID, out.TXT = s.Features.Raid, s.Features.Txt
+	}
+	return out
+}
+
+func operatingSystemObject(os packngo.OS) *v1alpha1.OperatingSystem {
+	o := &v1alpha1.OperatingSystem{}
+	o.SetName(objectName(os.Slug))
+	labels := map[string]string{
+		v1alpha1.LabelManagedBy: v1alpha1.ManagedByValue,
+		v1alpha1.LabelSlug:      labelValue(os.Slug),
+		v1alpha1.LabelDistro:    labelValue(os.Distro),
+		v1alpha1.LabelVersion:   labelValue(os.Version),
+	}
+	for _, p := range os.ProvisionableOn {
+		labels[v1alpha1.LabelPlanPrefix+labelValue(p)] = "true"
+	}
+	o.SetLabels(labels)
+
+	on := append([]string(nil), os.ProvisionableOn...)
+	sort.Strings(on)
+	o.Status.AtProvider = v1alpha1.OperatingSystemObservation{
+		Slug:            os.Slug,
+		Name:            os.Name,
+		Distro:          os.Distro,
+		Version:         os.Version,
+		ProvisionableOn: on,
+	}
+	return o
+}
+
+func price(p float32) string {
+	return strconv.FormatFloat(float64(p), 'f', -1, 32)
+}
+
+var (
+	invalidName  = regexp.MustCompile(`[^a-z0-9.-]+`)
+	invalidLabel = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
+// objectName returns the supplied slug as a valid object name. Slugs such as
+// ubuntu_20_04 contain underscores, which are replaced by hyphens.
+func objectName(slug string) string {
+	return strings.Trim(invalidName.ReplaceAllString(strings.ToLower(slug), "-"), "-.")
+}
+
+// labelValue returns the supplied string as a valid label value, or name
+// suffix.
+func labelValue(v string) string {
+	v = invalidLabel.ReplaceAllString(v, "-")
+	if len(v) > 63 {
+		v = v[:63]
+	}
+	return strings.Trim(v, "-._")
+}
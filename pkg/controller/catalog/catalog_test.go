@@ -0,0 +1,89 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package catalog
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/packethost/packngo"
+
+	"github.com/packethost/crossplane-provider-equinix-metal/apis/catalog/v1alpha1"
+)
+
+func TestPlanObject(t *testing.T) {
+	p := packngo.Plan{
+		ID:                "plan-id",
+		Slug:              "c3.small.x86",
+		Line:              "baremetal",
+		Class:             "c3.small.x86",
+		DeploymentTypes:   []string{"on_demand"},
+		Pricing:           &packngo.Pricing{Hour: 0.5},
+		Specs:             &packngo.Specs{Memory: &packngo.Memory{Total: "32GB"}},
+		AvailableInMetros: []packngo.Metro{{Code: "sv"}, {Code: "da"}},
+	}
+	capacity := &packngo.CapacityReport{"sv": {"c3.small.x86": {Level: "limited"}}}
+
+	want := &v1alpha1.Plan{}
+	want.SetName("c3.small.x86")
+	want.SetLabels(map[string]string{
+		v1alpha1.LabelManagedBy:                          v1alpha1.ManagedByValue,
+		v1alpha1.LabelSlug:                               "c3.small.x86",
+		v1alpha1.LabelClass:                              "c3.small.x86",
+		v1alpha1.LabelLine:                               "baremetal",
+		v1alpha1.LabelLegacy:                             "false",
+		v1alpha1.LabelDeploymentTypePrefix + "on_demand": "true",
+		v1alpha1.LabelMetroPrefix + "sv":                 "limited",
+		v1alpha1.LabelMetroPrefix + "da":                 capacityAvailable,
+	})
+	want.Status.AtProvider = v1alpha1.PlanObservation{
+		ID:              "plan-id",
+		Slug:            "c3.small.x86",
+		Line:            "baremetal",
+		Class:           "c3.small.x86",
+		DeploymentTypes: []string{"on_demand"},
+		Pricing:         v1alpha1.PlanPricing{Hour: "0.5", Month: "0"},
+		Specs:           v1alpha1.PlanSpecs{Memory: "32GB"},
+		Metros: []v1alpha1.PlanAvailability{
+			{Metro: "da"},
+			{Metro: "sv", Capacity: "limited"},
+		},
+	}
+
+	if diff := cmp.Diff(want, planObject(p, capacity)); diff != "" {
+		t.Errorf("planObject(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestObjectName(t *testing.T) {
+	cases := map[string]struct {
+		slug string
+		want string
+	}{
+		"Plan":          {slug: "c3.small.x86", want: "c3.small.x86"},
+		"Underscores":   {slug: "ubuntu_20_04", want: "ubuntu-20-04"},
+		"UpperCaseCode": {slug: "SV", want: "sv"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := objectName(tc.slug); got != tc.want {
+				t.Errorf("objectName(%q): want %q, got %q", tc.slug, tc.want, got)
+			}
+		})
+	}
+}
@@ -14,20 +14,41 @@ limitations under the License.
 package config
 
 import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 	"github.com/crossplane/crossplane-runtime/pkg/event"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
 	"github.com/crossplane/crossplane-runtime/pkg/reconciler/providerconfig"
 	"github.com/crossplane/crossplane-runtime/pkg/resource"
 
 	"github.com/packethost/crossplane-provider-equinix-metal/apis/v1beta1"
+	"github.com/packethost/crossplane-provider-equinix-metal/pkg/clients"
+)
+
+const (
+	errGetPC        = "cannot get ProviderConfig"
+	errUpdateStatus = "cannot update ProviderConfig status"
+	errGetAccount   = "cannot validate credentials"
+
+	// validationInterval is how often credentials that have not changed are
+	// validated again, in case they were revoked.
+	validationInterval = 1 * time.Hour
 )
 
 // Setup adds a controller that reconciles ProviderConfigs by accounting for
-// their current usage.
-func Setup(mgr ctrl.Manager, l logging.Logger) error {
+// their current usage and validating their credentials.
+func Setup(mgr ctrl.Manager, l logging.Logger, cc *clients.ClientCache) error {
 	name := providerconfig.ControllerName(v1beta1.ProviderConfigGroupKind)
 
 	of := resource.ProviderConfigKinds{
@@ -35,11 +56,156 @@ func Setup(mgr ctrl.Manager, l logging.Logger) error {
 		UsageList: v1beta1.ProviderConfigUsageListGroupVersionKind,
 	}
 
+	log := l.WithValues("controller", name)
+	recorder := event.NewAPIRecorder(mgr.GetEventRecorderFor(name))
+
+	r := &Reconciler{
+		Reconciler: providerconfig.NewReconciler(mgr, of,
+			providerconfig.WithLogger(log),
+			providerconfig.WithRecorder(recorder)),
+		kube:      mgr.GetClient(),
+		cache:     cc,
+		log:       log,
+		record:    recorder,
+		validated: map[string]validation{},
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		Named(name).
 		For(&v1beta1.ProviderConfig{}).
 		Watches(&source.Kind{Type: &v1beta1.ProviderConfigUsage{}}, &resource.EnqueueRequestForProviderConfig{}).
-		Complete(providerconfig.NewReconciler(mgr, of,
-			providerconfig.WithLogger(l.WithValues("controller", name)),
-			providerconfig.WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name)))))
+		Complete(r)
+}
+
+// A Reconciler accounts for the usage of a ProviderConfig using the wrapped
+// reconciler, then validates its credentials against the Equinix Metal API.
+type Reconciler struct {
+	reconcile.Reconciler
+
+	kube   client.Client
+	cache  *clients.ClientCache
+	log    logging.Logger
+	record event.Recorder
+
+	newClientFn func(ctx context.Context, pc *v1beta1.ProviderConfig) (AccountClient, string, error)
+
+	mu        sync.Mutex
+	validated map[string]validation
+}
+
+// An AccountClient describes the access granted by the credentials of a
+// ProviderConfig.
+type AccountClient interface {
+	GetAccount() (*clients.Account, error)
+}
+
+// A validation records when the credentials of a ProviderConfig were last
+// validated, and the version of the ProviderConfig and credentials that were
+// validated.
+type validation struct {
+	version string
+	at      time.Time
+}
+
+// Reconcile a ProviderConfig.
+func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	result, err := r.Reconciler.Reconcile(ctx, req)
+	if err != nil || result.Requeue || result.RequeueAfter > 0 {
+		return result, err
+	}
+
+	pc := &v1beta1.ProviderConfig{}
+	err = r.kube.Get(ctx, req.NamespacedName, pc)
+	if kerrors.IsNotFound(err) {
+		r.forget(req.Name)
+		return reconcile.Result{}, nil
+	}
+	if err != nil {
+		return reconcile.Result{}, errors.Wrap(err, errGetPC)
+	}
+	if meta.WasDeleted(pc) {
+		r.forget(pc.GetName())
+		return reconcile.Result{}, nil
+	}
+
+	newClientFn := r.clientFor
+	if r.newClientFn != nil {
+		newClientFn = r.newClientFn
+	}
+
+	cl, version, err := newClientFn(ctx, pc)
+	if err != nil {
+		return r.invalid(ctx, pc, v1beta1.InvalidCredentials(err))
+	}
+	if wait := r.revalidateIn(pc.GetName(), version); wait > 0 && pc.GetCondition(xpv1.TypeReady).Reason == v1beta1.ReasonCredentialsValid {
+		return reconcile.Result{RequeueAfter: wait}, nil
+	}
+
+	a, err := cl.GetAccount()
+	switch {
+	case err == nil:
+	case clients.IsThrottled(err), clients.IsTransient(err):
+		return reconcile.Result{RequeueAfter: clients.RequeueAfter(err)}, errors.Wrap(err, errGetAccount)
+	case clients.IsUnauthorized(err):
+		return r.invalid(ctx, pc, v1beta1.InvalidCredentials(errors.Wrap(err, errGetAccount)))
+	default:
+		return r.invalid(ctx, pc, v1beta1.ProjectInaccessible(errors.Wrap(err, errGetAccount)))
+	}
+
+	pc.Status.Organization = a.Organization
+	pc.Status.ProjectName = a.ProjectName
+	pc.Status.TokenScope = a.TokenScope
+	pc.Status.ReadOnly = a.ReadOnly
+	pc.SetConditions(v1beta1.CredentialsValid())
+	if err := r.kube.Status().Update(ctx, pc); err != nil {
+		return reconcile.Result{}, errors.Wrap(err, errUpdateStatus)
+	}
+
+	r.mu.Lock()
+	r.validated[pc.GetName()] = validation{version: version, at: time.Now()}
+	r.mu.Unlock()
+	return reconcile.Result{RequeueAfter: validationInterval}, nil
+}
+
+// invalid records that the credentials of the supplied ProviderConfig are not
+// usable, as described by the supplied condition.
+func (r *Reconciler) invalid(ctx context.Context, pc *v1beta1.ProviderConfig, c xpv1.Condition) (reconcile.Result, error) {
+	r.log.Debug("Invalid credentials", "name", pc.GetName(), "reason", c.Reason, "error", c.Message)
+	r.record.Event(pc, event.Warning(event.Reason(c.Reason), errors.New(c.Message)))
+	r.forget(pc.GetName())
+
+	pc.Status.Organization = ""
+	pc.Status.ProjectName = ""
+	pc.Status.TokenScope = ""
+	pc.Status.ReadOnly = false
+	pc.SetConditions(c)
+	return reconcile.Result{RequeueAfter: validationInterval}, errors.Wrap(r.kube.Status().Update(ctx, pc), errUpdateStatus)
+}
+
+// clientFor returns the cached client of the supplied ProviderConfig.
+func (r *Reconciler) clientFor(ctx context.Context, pc *v1beta1.ProviderConfig) (AccountClient, string, error) {
+	cl, version, err := r.cache.VersionedClientFor(ctx, r.kube, pc)
+	if err != nil {
+		return nil, "", err
+	}
+	return cl, version, nil
+}
+
+// revalidateIn returns how long the credentials of the named ProviderConfig
+// remain validated, if the supplied version of them was last validated.
+func (r *Reconciler) revalidateIn(name, version string) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v, ok := r.validated[name]
+	if !ok || v.version != version {
+		return 0
+	}
+	return time.Until(v.at.Add(validationInterval))
+}
+
+func (r *Reconciler) forget(name string) {
+	r.mu.Lock()
+	delete(r.validated, name)
+	r.mu.Unlock()
+	r.cache.Invalidate(name)
 }
@@ -0,0 +1,244 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/packethost/packngo"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	"github.com/packethost/crossplane-provider-equinix-metal/apis/v1beta1"
+	"github.com/packethost/crossplane-provider-equinix-metal/pkg/clients"
+)
+
+const (
+	pcName  = "cool-equinix-metal"
+	version = "1/cafe"
+)
+
+var errBoom = errors.New("boom")
+
+// accountFn is an AccountClient that returns the result of the function.
+type accountFn func() (*clients.Account, error)
+
+func (fn accountFn) GetAccount() (*clients.Account, error) { return fn() }
+
+func errResponse(code int) error {
+	req, _ := http.NewRequest(http.MethodGet, "https://api.equinix.com/metal/v1/projects/cool-project", nil)
+	return &packngo.ErrorResponse{Response: &http.Response{StatusCode: code, Request: req}}
+}
+
+type pcModifier func(*v1beta1.ProviderConfig)
+
+func withConditions(c ...xpv1.Condition) pcModifier {
+	return func(pc *v1beta1.ProviderConfig) { pc.SetConditions(c...) }
+}
+
+func withAccount(a clients.Account) pcModifier {
+	return func(pc *v1beta1.ProviderConfig) {
+		pc.Status.Organization = a.Organization
+		pc.Status.ProjectName = a.ProjectName
+		pc.Status.TokenScope = a.TokenScope
+		pc.Status.ReadOnly = a.ReadOnly
+	}
+}
+
+func providerConfig(m ...pcModifier) *v1beta1.ProviderConfig {
+	pc := &v1beta1.ProviderConfig{ObjectMeta: metav1.ObjectMeta{Name: pcName}}
+	for _, f := range m {
+		f(pc)
+	}
+	return pc
+}
+
+func TestReconcile(t *testing.T) {
+	account := clients.Account{Organization: "cool-org", ProjectName: "cool-project", TokenScope: v1beta1.TokenScopeUser}
+	unexpected := accountFn(func() (*clients.Account, error) {
+		return nil, errors.New("credentials were validated again")
+	})
+
+	type args struct {
+		pc        *v1beta1.ProviderConfig
+		client    AccountClient
+		version   string
+		validated map[string]validation
+	}
+	type want struct {
+		pc     *v1beta1.ProviderConfig
+		result reconcile.Result
+		err    error
+	}
+
+	cases := map[string]struct {
+		args args
+		want want
+	}{
+		"Valid": {
+			args: args{
+				pc:      providerConfig(),
+				client:  accountFn(func() (*clients.Account, error) { return &account, nil }),
+				version: version,
+			},
+			want: want{
+				pc:     providerConfig(withAccount(account), withConditions(v1beta1.CredentialsValid())),
+				result: reconcile.Result{RequeueAfter: validationInterval},
+			},
+		},
+		"ValidWithoutProject": {
+			args: args{
+				pc:      providerConfig(),
+				client:  accountFn(func() (*clients.Account, error) { return &clients.Account{TokenScope: v1beta1.TokenScopeUser}, nil }),
+				version: version,
+			},
+			want: want{
+				pc:     providerConfig(withAccount(clients.Account{TokenScope: v1beta1.TokenScopeUser}), withConditions(v1beta1.CredentialsValid())),
+				result: reconcile.Result{RequeueAfter: validationInterval},
+			},
+		},
+		"Unauthorized": {
+			args: args{
+				pc:      providerConfig(withAccount(account), withConditions(v1beta1.CredentialsValid())),
+				client:  accountFn(func() (*clients.Account, error) { return nil, errResponse(http.StatusUnauthorized) }),
+				version: version,
+			},
+			want: want{
+				pc:     providerConfig(withConditions(v1beta1.InvalidCredentials(errors.Wrap(errResponse(http.StatusUnauthorized), errGetAccount)))),
+				result: reconcile.Result{RequeueAfter: validationInterval},
+			},
+		},
+		"ProjectDenied": {
+			args: args{
+				pc:      providerConfig(),
+				client:  accountFn(func() (*clients.Account, error) { return nil, errResponse(http.StatusForbidden) }),
+				version: version,
+			},
+			want: want{
+				pc:     providerConfig(withConditions(v1beta1.ProjectInaccessible(errors.Wrap(errResponse(http.StatusForbidden), errGetAccount)))),
+				result: reconcile.Result{RequeueAfter: validationInterval},
+			},
+		},
+		"RecentlyValidated": {
+			args: args{
+				pc:        providerConfig(withAccount(account), withConditions(v1beta1.CredentialsValid())),
+				client:    unexpected,
+				version:   version,
+				validated: map[string]validation{pcName: {version: version, at: time.Now().Add(-validationInterval / 2)}},
+			},
+			want: want{
+				pc:     providerConfig(withAccount(account), withConditions(v1beta1.CredentialsValid())),
+				result: reconcile.Result{RequeueAfter: validationInterval / 2},
+			},
+		},
+		"ValidationIntervalPassed": {
+			args: args{
+				pc:        providerConfig(withAccount(account), withConditions(v1beta1.CredentialsValid())),
+				client:    accountFn(func() (*clients.Account, error) { return nil, errResponse(http.StatusUnauthorized) }),
+				version:   version,
+				validated: map[string]validation{pcName: {version: version, at: time.Now().Add(-2 * validationInterval)}},
+			},
+			want: want{
+				pc:     providerConfig(withConditions(v1beta1.InvalidCredentials(errors.Wrap(errResponse(http.StatusUnauthorized), errGetAccount)))),
+				result: reconcile.Result{RequeueAfter: validationInterval},
+			},
+		},
+		"CredentialsChanged": {
+			args: args{
+				pc:        providerConfig(withAccount(account), withConditions(v1beta1.CredentialsValid())),
+				client:    accountFn(func() (*clients.Account, error) { return nil, errResponse(http.StatusUnauthorized) }),
+				version:   "2/cafe",
+				validated: map[string]validation{pcName: {version: version, at: time.Now()}},
+			},
+			want: want{
+				pc:     providerConfig(withConditions(v1beta1.InvalidCredentials(errors.Wrap(errResponse(http.StatusUnauthorized), errGetAccount)))),
+				result: reconcile.Result{RequeueAfter: validationInterval},
+			},
+		},
+		"UnreadableCredentials": {
+			args: args{
+				pc:      providerConfig(withAccount(account), withConditions(v1beta1.CredentialsValid())),
+				version: version,
+			},
+			want: want{
+				pc:     providerConfig(withConditions(v1beta1.InvalidCredentials(errBoom))),
+				result: reconcile.Result{RequeueAfter: validationInterval},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got *v1beta1.ProviderConfig
+			validated := tc.args.validated
+			if validated == nil {
+				validated = map[string]validation{}
+			}
+			r := &Reconciler{
+				Reconciler: reconcile.Func(func(context.Context, reconcile.Request) (reconcile.Result, error) {
+					return reconcile.Result{}, nil
+				}),
+				kube: &test.MockClient{
+					MockGet: test.NewMockGetFn(nil, func(obj client.Object) error {
+						tc.args.pc.DeepCopyInto(obj.(*v1beta1.ProviderConfig))
+						return nil
+					}),
+					MockStatusUpdate: func(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+						got = obj.(*v1beta1.ProviderConfig)
+						return nil
+					},
+				},
+				log:    logging.NewNopLogger(),
+				record: event.NewNopRecorder(),
+				newClientFn: func(context.Context, *v1beta1.ProviderConfig) (AccountClient, string, error) {
+					if tc.args.client == nil {
+						return nil, "", errBoom
+					}
+					return tc.args.client, tc.args.version, nil
+				},
+				validated: validated,
+			}
+
+			result, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: pcName}})
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("r.Reconcile(...): -want error, +got error:\n%s", diff)
+			}
+			result.RequeueAfter = result.RequeueAfter.Round(time.Minute)
+			if diff := cmp.Diff(tc.want.result, result); diff != "" {
+				t.Errorf("r.Reconcile(...): -want result, +got result:\n%s", diff)
+			}
+			if got == nil {
+				got = tc.args.pc
+			}
+			if diff := cmp.Diff(tc.want.pc, got, test.EquateConditions()); diff != "" {
+				t.Errorf("r.Reconcile(...): -want ProviderConfig, +got ProviderConfig:\n%s", diff)
+			}
+		})
+	}
+}
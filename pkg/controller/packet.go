@@ -17,24 +17,67 @@ limitations under the License.
 package controller
 
 import (
+	"time"
+
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 
+	"github.com/packethost/crossplane-provider-equinix-metal/pkg/clients"
+	"github.com/packethost/crossplane-provider-equinix-metal/pkg/controller/catalog"
+	"github.com/packethost/crossplane-provider-equinix-metal/pkg/controller/config"
 	"github.com/packethost/crossplane-provider-equinix-metal/pkg/controller/ports/assignment"
 	"github.com/packethost/crossplane-provider-equinix-metal/pkg/controller/server/device"
 	"github.com/packethost/crossplane-provider-equinix-metal/pkg/controller/vlan/virtualnetwork"
 )
 
+// Options configure the Equinix Metal controllers.
+type Options struct {
+	// SnapshotInterval is the interval at which the Devices and
+	// VirtualNetworks of each project are listed in order to observe them
+	// without reading each resource. Resources are read individually when the
+	// interval is zero.
+	SnapshotInterval time.Duration
+
+	// CatalogProviderConfig is the name of the ProviderConfig whose
+	// credentials are used to populate the Metro, Plan and OperatingSystem
+	// resources.
+	CatalogProviderConfig string
+
+	// CatalogSyncInterval is the interval at which the Metro, Plan and
+	// OperatingSystem resources are populated. They are not populated when
+	// the interval is zero.
+	CatalogSyncInterval time.Duration
+}
+
 // Setup creates all Equinix Metal controllers with the supplied logger and adds them to
 // the supplied manager.
-func Setup(mgr ctrl.Manager, l logging.Logger) error {
-	for _, setup := range []func(ctrl.Manager, logging.Logger) error{
+func Setup(mgr ctrl.Manager, l logging.Logger, o Options) error {
+	co := []clients.ClientCacheOption{
+		clients.WithCatalog(clients.NewCatalog(clients.DefaultCatalogTTL, clients.DefaultCapacityTTL)),
+	}
+	if o.SnapshotInterval > 0 {
+		s := clients.NewSnapshotter(o.SnapshotInterval, l.WithValues("component", "snapshotter"))
+		if err := mgr.Add(s); err != nil {
+			return err
+		}
+		co = append(co, clients.WithSnapshotter(s))
+	}
+	cc := clients.NewClientCache(co...)
+
+	for _, setup := range []func(ctrl.Manager, logging.Logger, *clients.ClientCache) error{
+		config.Setup,
 		assignment.SetupAssignment,
 		device.SetupDevice,
 		virtualnetwork.SetupVirtualNetwork,
 	} {
-		if err := setup(mgr, l); err != nil {
+		if err := setup(mgr, l, cc); err != nil {
+			return err
+		}
+	}
+
+	if o.CatalogSyncInterval > 0 {
+		if err := catalog.Setup(mgr, l, cc, o.CatalogProviderConfig, o.CatalogSyncInterval); err != nil {
 			return err
 		}
 	}
@@ -24,12 +24,14 @@ import (
 	"github.com/pkg/errors"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 
 	"github.com/packethost/crossplane-provider-equinix-metal/apis/ports/v1alpha1"
 	packetv1beta1 "github.com/packethost/crossplane-provider-equinix-metal/apis/v1beta1"
 	"github.com/packethost/crossplane-provider-equinix-metal/pkg/clients"
 	packetclient "github.com/packethost/crossplane-provider-equinix-metal/pkg/clients"
 	portsclient "github.com/packethost/crossplane-provider-equinix-metal/pkg/clients/ports"
+	"github.com/packethost/crossplane-provider-equinix-metal/pkg/tracing"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 	"github.com/crossplane/crossplane-runtime/pkg/event"
@@ -50,15 +52,27 @@ const (
 	errDeleteAssignment        = "cannot delete Assignment"
 )
 
+// errorReasons are the error messages by which the reconcile errors of the
+// controller are counted.
+var errorReasons = []string{
+	errNotAssignment,
+	errGetPort,
+	errCreateAssignment,
+	errDeleteAssignment,
+}
+
 // SetupAssignment adds a controller that reconciles Assignments
-func SetupAssignment(mgr ctrl.Manager, l logging.Logger) error {
+func SetupAssignment(mgr ctrl.Manager, l logging.Logger, cc *clients.ClientCache) error {
 	name := managed.ControllerName(v1alpha1.AssignmentGroupKind)
 
+	rl := clients.NewRateLimiter(nil)
 	r := managed.NewReconciler(mgr,
 		resource.ManagedKind(v1alpha1.AssignmentGroupVersionKind),
 		managed.WithExternalConnecter(&connecter{
-			kube:  mgr.GetClient(),
-			usage: resource.NewProviderConfigUsageTracker(mgr.GetClient(), &packetv1beta1.ProviderConfigUsage{}),
+			kube:    mgr.GetClient(),
+			usage:   resource.NewProviderConfigUsageTracker(mgr.GetClient(), &packetv1beta1.ProviderConfigUsage{}),
+			limiter: rl,
+			cache:   cc,
 		}),
 		managed.WithInitializers(&managed.DefaultProviderConfig{}),
 		managed.WithConnectionPublishers(),
@@ -69,6 +83,7 @@ func SetupAssignment(mgr ctrl.Manager, l logging.Logger) error {
 
 	return ctrl.NewControllerManagedBy(mgr).
 		Named(name).
+		WithOptions(controller.Options{RateLimiter: rl}).
 		For(&v1alpha1.Assignment{}).
 		Complete(r)
 }
@@ -76,7 +91,9 @@ func SetupAssignment(mgr ctrl.Manager, l logging.Logger) error {
 type connecter struct {
 	kube        client.Client
 	usage       resource.Tracker
-	newClientFn func(ctx context.Context, config *clients.Credentials) (portsclient.ClientWithDefaults, error)
+	limiter     *clients.RateLimiter
+	cache       *clients.ClientCache
+	newClientFn func(ctx context.Context, client *clients.Client) (portsclient.ClientWithDefaults, error)
 }
 
 func (c *connecter) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
@@ -88,17 +105,19 @@ func (c *connecter) Connect(ctx context.Context, mg resource.Managed) (managed.E
 		return nil, errors.Wrap(err, errTrackPCUsage)
 	}
 
-	newClientFn := portsclient.NewClient
+	newClientFn := portsclient.NewClientFromAPI
 	if c.newClientFn != nil {
 		newClientFn = c.newClientFn
 	}
-	cfg, err := clients.GetAuthInfo(ctx, c.kube, mg)
+	cl, err := c.cache.GetClient(ctx, c.kube, mg)
 	if err != nil {
 		return nil, errors.Wrap(err, errGetProviderConfigSecret)
 	}
-	client, err := newClientFn(ctx, cfg)
+	scope := tracing.NewScope()
+	client, err := newClientFn(ctx, cl.WithScope(scope))
 
-	return &external{kube: c.kube, client: client}, errors.Wrap(err, errNewClient)
+	e := &external{kube: c.kube, client: client}
+	return tracing.NewExternalClient(clients.NewClassifyingExternalClient(e, c.limiter, errorReasons...), scope), errors.Wrap(err, errNewClient)
 }
 
 type external struct {
@@ -19,19 +19,30 @@ package device
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/packethost/packngo"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	v1alpha2 "github.com/packethost/crossplane-provider-equinix-metal/apis/server/v1alpha2"
 	packetv1beta1 "github.com/packethost/crossplane-provider-equinix-metal/apis/v1beta1"
 	"github.com/packethost/crossplane-provider-equinix-metal/pkg/clients"
 	packetclient "github.com/packethost/crossplane-provider-equinix-metal/pkg/clients"
 	devicesclient "github.com/packethost/crossplane-provider-equinix-metal/pkg/clients/device"
+	"github.com/packethost/crossplane-provider-equinix-metal/pkg/metrics"
+	"github.com/packethost/crossplane-provider-equinix-metal/pkg/tracing"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 	"github.com/crossplane/crossplane-runtime/pkg/event"
@@ -51,40 +62,131 @@ const (
 	errNotDevice               = "managed resource is not a Device"
 	errGetDevice               = "cannot get Device"
 	errCreateDevice            = "cannot create Device"
+	errCheckCatalog            = "cannot check the plan, operating system and location against the catalog"
+	errInvalidStorage          = "invalid storage layout"
 	errUpdateDevice            = "cannot modify Device"
+	errReinstallDevice         = "cannot reinstall Device"
 	errDeleteDevice            = "cannot delete Device"
+	errDeleteExpired           = "cannot delete expired Device"
+	errExpiringFmt             = "the Device will be deleted in %s, when its termination time passes"
+	errConvertNetworkStepFmt   = "cannot convert Device network type at step %s"
+	errNetworkTypeUnchangedFmt = "network type of Device is not %s after converting it, but %s"
+	errAssignIPAddressFmt      = "cannot assign IP address %s to Device"
+	errUnassignIPAddressFmt    = "cannot unassign IP address %s from Device"
+	errAvailableAddressesFmt   = "cannot list available addresses of IP reservation %s"
+	errNoAvailableAddressFmt   = "no addresses are available in IP reservations %v"
+	errIPAddressNoReservations = "cannot add an IP address to a created Device without ip_reservations to draw it from"
+	errRescueDevice            = "cannot reboot Device into rescue mode"
+	errExitRescue              = "cannot reboot Device out of rescue mode"
+	errUnlockDevice            = "cannot unlock Device before deleting it"
+	errDeviceLocked            = "cannot delete a locked Device; unlock it, or set spec.forProvider.unlockBeforeDelete"
+	errDeviceProvisioningFmt   = "cannot delete a Device that is %s; set spec.forProvider.forceDelete to delete it anyway"
+	errIndexUserDataRefs       = "cannot index Devices by the ConfigMaps and Secrets their userdata is resolved from"
+	errGetTemplateResourceFmt  = "cannot get resource %q referenced by the userdata template"
+	errGetTemplateSecretFmt    = "cannot get Secret %q referenced by the userdata template"
+	errUserDataSourceFmt       = "cannot resolve userdata source %d"
 
 	userdataMapKey = "cloud-init"
 )
 
+// errorReasons are the error messages by which the reconcile errors of the
+// controller are counted.
+var errorReasons = []string{
+	errManagedUpdateFailed,
+	errGenObservation,
+	errNotDevice,
+	errGetDevice,
+	errCreateDevice,
+	errInvalidStorage,
+	errUpdateDevice,
+	errReinstallDevice,
+	errDeleteDevice,
+	errDeleteExpired,
+	errExpiringFmt,
+	errConvertNetworkStepFmt,
+	errNetworkTypeUnchangedFmt,
+	errAssignIPAddressFmt,
+	errUnassignIPAddressFmt,
+	errAvailableAddressesFmt,
+	errNoAvailableAddressFmt,
+	errIPAddressNoReservations,
+	errRescueDevice,
+	errExitRescue,
+	errUnlockDevice,
+	errDeviceLocked,
+	errDeviceProvisioningFmt,
+	errGetTemplateResourceFmt,
+	errGetTemplateSecretFmt,
+	errUserDataSourceFmt,
+}
+
+// Event reasons.
+const (
+	reasonDeletionBlocked    event.Reason = "DeletionBlocked"
+	reasonExpiring           event.Reason = "Expiring"
+	reasonExpired            event.Reason = "Expired"
+	reasonRescue             event.Reason = "Rescue"
+	reasonCatalogUnavailable event.Reason = "CatalogUnavailable"
+)
+
+const (
+	// expiryWarning is how long before its termination time a Device is
+	// reported to be expiring.
+	expiryWarning = time.Hour
+
+	// terminationSkew is how long before its termination time a Device is
+	// deleted, to allow for the API deleting it first.
+	terminationSkew = time.Minute
+)
+
 // SetupDevice adds a controller that reconciles Devices
-func SetupDevice(mgr ctrl.Manager, l logging.Logger) error {
+func SetupDevice(mgr ctrl.Manager, l logging.Logger, cc *clients.ClientCache) error {
 	name := managed.ControllerName(v1alpha2.DeviceGroupKind)
+	log := l.WithValues("controller", name)
+	recorder := event.NewAPIRecorder(mgr.GetEventRecorderFor(name))
 
+	rl := clients.NewRateLimiter(nil)
 	r := managed.NewReconciler(mgr,
 		resource.ManagedKind(v1alpha2.DeviceGroupVersionKind),
 		managed.WithExternalConnecter(&connecter{
-			kube:  mgr.GetClient(),
-			usage: resource.NewProviderConfigUsageTracker(mgr.GetClient(), &packetv1beta1.ProviderConfigUsage{}),
+			kube:     mgr.GetClient(),
+			usage:    resource.NewProviderConfigUsageTracker(mgr.GetClient(), &packetv1beta1.ProviderConfigUsage{}),
+			limiter:  rl,
+			cache:    cc,
+			recorder: recorder,
 		}),
-		managed.WithLogger(l.WithValues("controller", name)),
-		managed.WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
+		managed.WithReferenceResolver(managed.NewAPISimpleReferenceResolver(mgr.GetClient())),
+		managed.WithLogger(log),
+		managed.WithRecorder(recorder),
 	)
 
+	// Devices are reconciled when the ConfigMaps and Secrets their userdata
+	// is resolved from change.
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &v1alpha2.Device{}, userDataRefsIndex, indexUserDataRefs); err != nil {
+		return errors.Wrap(err, errIndexUserDataRefs)
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		Named(name).
+		WithOptions(controller.Options{RateLimiter: rl}).
 		For(&v1alpha2.Device{}).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, handler.EnqueueRequestsFromMapFunc(enqueueReferencingDevices(mgr.GetClient(), log, "ConfigMap"))).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(enqueueReferencingDevices(mgr.GetClient(), log, "Secret"))).
 		Complete(r)
 }
 
 type connecter struct {
 	kube        client.Client
 	usage       resource.Tracker
-	newClientFn func(ctx context.Context, config *clients.Credentials) (devicesclient.ClientWithDefaults, error)
+	limiter     *clients.RateLimiter
+	cache       *clients.ClientCache
+	recorder    event.Recorder
+	newClientFn func(ctx context.Context, client *clients.Client) (devicesclient.ClientWithDefaults, error)
 }
 
 func (c *connecter) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
-	if _, ok := mg.(*v1alpha2.Device); !ok {
+	d, ok := mg.(*v1alpha2.Device)
+	if !ok {
 		return nil, errors.New(errNotDevice)
 	}
 
@@ -92,23 +194,36 @@ func (c *connecter) Connect(ctx context.Context, mg resource.Managed) (managed.E
 		return nil, errors.Wrap(err, errTrackPCUsage)
 	}
 
-	newClientFn := devicesclient.NewClient
+	newClientFn := devicesclient.NewClientFromAPI
 	if c.newClientFn != nil {
 		newClientFn = c.newClientFn
 	}
 
-	cfg, err := clients.GetAuthInfo(ctx, c.kube, mg)
+	cl, err := c.cache.GetClient(ctx, c.kube, mg)
 	if err != nil {
 		return nil, errors.Wrap(err, errGetProviderConfigSecret)
 	}
-	client, err := newClientFn(ctx, cfg)
-
-	return &external{kube: c.kube, client: client}, errors.Wrap(err, errNewClient)
+	scope := tracing.NewScope()
+	client, err := newClientFn(ctx, cl.WithScope(scope))
+
+	e := &external{
+		kube:     c.kube,
+		recorder: c.recorder,
+		client:   client,
+		projects: cl.Client.Projects,
+		catalog:  c.cache.Catalog(cl),
+		snapshot: c.cache.Snapshot(cl, cl.GetProjectID(d.Spec.ForProvider.ProjectID)),
+	}
+	return tracing.NewExternalClient(clients.NewClassifyingExternalClient(e, c.limiter, errorReasons...), scope), errors.Wrap(err, errNewClient)
 }
 
 type external struct {
-	kube   client.Client
-	client devicesclient.ClientWithDefaults
+	kube     client.Client
+	recorder event.Recorder
+	client   devicesclient.ClientWithDefaults
+	projects clients.ProjectClient
+	catalog  clients.CatalogClient
+	snapshot *clients.ProjectSnapshot
 }
 
 func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) { //nolint:gocyclo
@@ -117,15 +232,33 @@ func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 		return managed.ExternalObservation{}, errors.New(errNotDevice)
 	}
 
+	// Devices are deleted shortly before their termination time, whether or
+	// not the API deletes them, so that they are not created again.
+	if t := devicesclient.TerminationTime(d); t != nil && !meta.WasDeleted(d) && time.Until(t.Time) < terminationSkew {
+		e.recorder.Event(d, event.Normal(reasonExpired, "Deleting the Device, as its termination time has passed"))
+		if err := e.kube.Delete(ctx, d); resource.IgnoreNotFound(err) != nil {
+			return managed.ExternalObservation{}, errors.Wrap(err, errDeleteExpired)
+		}
+		return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}, nil
+	}
+
 	// Observe device
-	device, _, err := e.client.Get(meta.GetExternalName(d), nil)
+	device, err := e.getDevice(meta.GetExternalName(d))
 	if packetclient.IsNotFound(err) {
+		metrics.ForgetDevice(d.GetName())
 		return managed.ExternalObservation{ResourceExists: false}, nil
 	}
 	if err != nil {
 		return managed.ExternalObservation{}, errors.Wrap(err, errGetDevice)
 	}
 
+	// A deleted Device is gone once it is deprovisioning, so that its
+	// finalizer is removed without waiting for the device to be reclaimed.
+	if meta.WasDeleted(d) && device.State == v1alpha2.StateDeprovisioning {
+		metrics.ForgetDevice(d.GetName())
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
+
 	current := d.Spec.ForProvider.DeepCopy()
 	devicesclient.LateInitialize(&d.Spec.ForProvider, device)
 	if !cmp.Equal(current, &d.Spec.ForProvider) {
@@ -134,10 +267,20 @@ func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 		}
 	}
 
+	previousState := d.Status.AtProvider.State
+	conversion, rescue := d.Status.AtProvider.NetworkConversion, d.Status.AtProvider.RescueAction
 	d.Status.AtProvider, err = devicesclient.GenerateObservation(device)
 	if err != nil {
 		return managed.ExternalObservation{}, errors.Wrap(err, errGenObservation)
 	}
+	d.Status.AtProvider.NetworkConversion = conversion
+
+	// A requested rescue mode change is complete once the mode is observed.
+	if rescue != nil && (rescue.Action == v1alpha2.RescueActionRescue) != d.Status.AtProvider.Rescued {
+		d.Status.AtProvider.RescueAction = rescue
+	}
+	recordState(d, previousState)
+	e.observeTermination(d)
 
 	// Set Device status and bindable
 	switch d.Status.AtProvider.State {
@@ -154,25 +297,143 @@ func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 		d.Status.SetConditions(xpv1.Unavailable())
 	}
 
-	upToDate, networkTypeUpToDate := devicesclient.IsUpToDate(d, device)
+	// Userdata resolved from ConfigMaps and Secrets is compared so that
+	// changes to them are applied. Userdata that cannot be resolved is
+	// reported, rather than preventing the Device from being observed.
+	var userdata *string
+	if devicesclient.ResolvesUserData(d.Spec.ForProvider) && !meta.WasDeleted(d) {
+		u, reason, err := e.userData(ctx, d)
+		switch {
+		case err != nil:
+			if reason == "" {
+				reason = v1alpha2.ReasonTemplateReferenceUnresolved
+			}
+			d.Status.SetConditions(v1alpha2.UserDataUnrendered(reason, err))
+		case reason != "":
+			d.Status.SetConditions(v1alpha2.UserDataRendered())
+			userdata = u
+		default:
+			userdata = u
+		}
+	}
+
+	// IP addresses are compared with those assigned to the device regardless
+	// of the reservations they are drawn from, which are only resolved when
+	// the Device is updated, rather than getting each of them whenever it is
+	// observed.
+	upToDate, networkTypeUpToDate := devicesclient.IsUpToDate(d, device, userdata, nil)
+
+	// A network type conversion is complete once the network type is observed.
+	if conversion != nil && networkTypeUpToDate {
+		d.Status.SetConditions(v1alpha2.NetworkTypeConverted(*conversion))
+		d.Status.AtProvider.NetworkConversion = nil
+	}
 
 	o := managed.ExternalObservation{
 		ResourceExists:    true,
 		ResourceUpToDate:  upToDate && networkTypeUpToDate,
-		ConnectionDetails: devicesclient.GetConnectionDetails(device),
+		ConnectionDetails: devicesclient.GetConnectionDetails(d, device),
+	}
+
+	// A Device in rescue mode is not changed until it leaves it, and its
+	// connection details are those of the rescue operating system. These are
+	// only known once the Device is observed in rescue mode, rather than when
+	// it is rebooted into it.
+	rescued := d.Status.AtProvider.Rescued
+	if rescued {
+		o.ResourceUpToDate = true
+		o.ConnectionDetails = devicesclient.RescueConnectionDetails(device, true)
+		for k, v := range devicesclient.SOSConnectionDetails(d, device) {
+			o.ConnectionDetails[k] = v
+		}
+	}
+
+	// A change of rescue mode takes minutes, and is waited for rather than
+	// requested again on every reconcile.
+	if d.Spec.ForProvider.Rescue != rescued {
+		o.ResourceUpToDate = devicesclient.RescueInProgress(d.Status.AtProvider, time.Now())
 	}
 
 	return o, nil
 }
 
+// observeTermination records when the supplied Device is deleted, and warns
+// when that is approaching.
+func (e *external) observeTermination(d *v1alpha2.Device) {
+	obs := &d.Status.AtProvider
+	if t := devicesclient.TerminationTime(d); t != nil && (obs.TerminationTime == nil || t.Before(obs.TerminationTime)) {
+		obs.TerminationTime = t
+	}
+
+	expiring := d.Status.GetCondition(v1alpha2.TypeExpiring).Status == corev1.ConditionTrue
+	if obs.TerminationTime == nil {
+		if expiring {
+			d.Status.SetConditions(v1alpha2.NotExpiring())
+		}
+		return
+	}
+
+	remaining := time.Until(obs.TerminationTime.Time)
+	if remaining < 0 {
+		remaining = 0
+	}
+	obs.RemainingLifetime = duration.HumanDuration(remaining)
+
+	switch {
+	case remaining <= expiryWarning && !expiring:
+		d.Status.SetConditions(v1alpha2.Expiring(*obs.TerminationTime))
+		e.recorder.Event(d, event.Warning(reasonExpiring, errors.Errorf(errExpiringFmt, obs.RemainingLifetime)))
+	case remaining > expiryWarning && expiring:
+		d.Status.SetConditions(v1alpha2.NotExpiring())
+	}
+}
+
+// recordState records the observed state of the Device, and how long it took to
+// provision when it was observed to become active.
+func recordState(d *v1alpha2.Device, previousState string) {
+	state := d.Status.AtProvider.State
+	metrics.SetDeviceState(d.GetName(), state)
+	if state != v1alpha2.StateActive || previousState == v1alpha2.StateActive || previousState == "" {
+		return
+	}
+	if created := d.Status.AtProvider.CreatedAt; created != nil {
+		metrics.ObserveProvisioned(time.Since(created.Time))
+	}
+}
+
+// getDevice returns the Device from the project snapshot, unless it is missing
+// from the snapshot or is changing state, in which case it is read from the
+// API.
+func (e *external) getDevice(id string) (*packngo.Device, error) {
+	if device, ok := e.snapshot.Device(id); ok && !isTransitional(device.State) {
+		return device, nil
+	}
+	device, _, err := e.client.Get(id, nil)
+	return device, err
+}
+
+// isTransitional returns true if a Device in the supplied state is expected to
+// change state shortly.
+func isTransitional(state string) bool {
+	switch state {
+	case v1alpha2.StateQueued,
+		v1alpha2.StateProvisioning,
+		v1alpha2.StateDeprovisioning,
+		v1alpha2.StateReinstalling,
+		v1alpha2.StatePoweringOn,
+		v1alpha2.StatePoweringOff:
+		return true
+	}
+	return false
+}
+
 // resolveUserDataRefs returns a userdata string fetched from the referenced userdata resource
 // TODO(displague) use reference.NewAPIResolver when TypedReference is support
-func (e *external) resolveUserDataRefs(ctx context.Context, d *v1alpha2.Device) (string, error) { //nolint:gocyclo
+func (e *external) resolveUserDataRefs(ctx context.Context, ref *v1alpha2.DataKeySelector) (string, error) { //nolint:gocyclo
 	errGetUserDataRef := "cannot get required resource for UserDataRef"
 	errInvalidRefKind := "invalid resource kind"
 	errRefKeyNotFoundFmt := "could not find UserDataRef key %q"
 
-	ref := d.Spec.ForProvider.UserDataRef
 	var userdata string
 	var ok bool
 	nsn := types.NamespacedName{
@@ -213,6 +474,101 @@ func (e *external) resolveUserDataRefs(ctx context.Context, d *v1alpha2.Device)
 	return userdata, nil
 }
 
+// renderUserData renders the supplied userdata template for the supplied
+// Device, with the resources and Secrets its userdata template references. It
+// returns the reason the userdata could not be rendered with any error.
+func (e *external) renderUserData(ctx context.Context, d *v1alpha2.Device, tmpl string) (string, xpv1.ConditionReason, error) {
+	t := d.Spec.ForProvider.UserDataTemplate
+
+	// Templates are rendered with the location the Device is created in,
+	// whether it is being created or has been observed.
+	facility, metro := packetclient.DefaultLocation(e.client, d.Spec.ForProvider.Facility, d.Spec.ForProvider.Metro)
+	data := devicesclient.TemplateData{
+		Device:    devicesclient.TemplateDevice(d, facility, metro),
+		Resources: make(map[string]map[string]interface{}, len(t.Resources)),
+		Secrets:   make(map[string]map[string]string, len(t.Secrets)),
+	}
+
+	for _, r := range t.Resources {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(r.ResourceRef.GroupVersionKind())
+		if err := e.kube.Get(ctx, types.NamespacedName{Name: r.ResourceRef.Name}, u); err != nil {
+			return "", v1alpha2.ReasonTemplateReferenceUnresolved, errors.Wrapf(err, errGetTemplateResourceFmt, r.Name)
+		}
+		data.Resources[r.Name] = u.Object
+	}
+
+	for _, r := range t.Secrets {
+		s := &corev1.Secret{}
+		if err := e.kube.Get(ctx, types.NamespacedName{Namespace: r.SecretRef.Namespace, Name: r.SecretRef.Name}, s); err != nil {
+			return "", v1alpha2.ReasonTemplateReferenceUnresolved, errors.Wrapf(err, errGetTemplateSecretFmt, r.Name)
+		}
+		values := make(map[string]string, len(s.Data))
+		for k, v := range s.Data {
+			values[k] = string(v)
+		}
+		data.Secrets[r.Name] = values
+	}
+
+	userdata, err := devicesclient.RenderUserData(tmpl, data)
+	if err != nil {
+		return "", v1alpha2.ReasonTemplateRenderFailed, err
+	}
+	return userdata, v1alpha2.ReasonUserDataRendered, nil
+}
+
+// userData returns the userdata the supplied Device is created with. Userdata
+// that is referenced, or assembled from sources, is resolved and rendered.
+// The reason it could not be, or was, rendered is returned for userdata that
+// is templated or assembled, and is empty otherwise.
+func (e *external) userData(ctx context.Context, d *v1alpha2.Device) (*string, xpv1.ConditionReason, error) {
+	fp := d.Spec.ForProvider
+
+	switch {
+	case fp.UserDataRef != nil:
+		userdata, err := e.resolveUserDataRefs(ctx, fp.UserDataRef)
+		if err != nil {
+			return nil, "", err
+		}
+		if fp.UserDataTemplate == nil {
+			return &userdata, "", nil
+		}
+		userdata, reason, err := e.renderUserData(ctx, d, userdata)
+		return &userdata, reason, err
+
+	case len(fp.UserDataSources) > 0:
+		parts := make([]devicesclient.UserDataPart, len(fp.UserDataSources))
+		for i, src := range fp.UserDataSources {
+			var content string
+			if src.Inline != nil {
+				content = *src.Inline
+			}
+			if src.Ref != nil {
+				c, err := e.resolveUserDataRefs(ctx, src.Ref)
+				if err != nil {
+					return nil, v1alpha2.ReasonTemplateReferenceUnresolved, errors.Wrapf(err, errUserDataSourceFmt, i)
+				}
+				content = c
+			}
+			if fp.UserDataTemplate != nil {
+				c, reason, err := e.renderUserData(ctx, d, content)
+				if err != nil {
+					return nil, reason, errors.Wrapf(err, errUserDataSourceFmt, i)
+				}
+				content = c
+			}
+			parts[i] = devicesclient.UserDataPart{Content: content, ContentType: src.ContentType, Filename: src.Filename}
+		}
+		userdata, err := devicesclient.MultipartUserData(parts, fp.UserDataGzip)
+		if err != nil {
+			return nil, v1alpha2.ReasonTemplateRenderFailed, err
+		}
+		return &userdata, v1alpha2.ReasonUserDataRendered, nil
+	}
+
+	return fp.UserData, "", nil
+}
+
 func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
 	d, ok := mg.(*v1alpha2.Device)
 	if !ok {
@@ -221,14 +577,54 @@ func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.Ext
 
 	d.Status.SetConditions(xpv1.Creating())
 
+	// The storage layout is validated here as well as by the webhook, as the
+	// webhook is not always enabled.
+	if errs := devicesclient.ValidateStorage(field.NewPath("spec", "forProvider", "storage"), d.Spec.ForProvider.Storage); len(errs) > 0 {
+		return managed.ExternalCreation{}, errors.Wrap(errs.ToAggregate(), errInvalidStorage)
+	}
+
 	createDev := d.DeepCopy()
 
-	if d.Spec.ForProvider.UserDataRef != nil {
-		userdata, err := e.resolveUserDataRefs(ctx, d)
-		if err != nil {
+	if p := d.Spec.ForProvider.ProjectID; p != "" {
+		if err := packetclient.ValidateProject(e.projects, p); err != nil {
+			return managed.ExternalCreation{}, errors.Wrap(err, errCreateDevice)
+		}
+	}
+
+	// Devices that specify neither facility nor metro are created in the
+	// default location of the ProviderConfig, which is recorded in their
+	// spec and status, so that it does not change with the ProviderConfig.
+	fp := &createDev.Spec.ForProvider
+	fp.Facility, fp.Metro = packetclient.DefaultLocation(e.client, fp.Facility, fp.Metro)
+
+	// Templated and assembled userdata is reported in its own condition, as
+	// the resources it references may not be observed yet.
+	userdata, reason, err := e.userData(ctx, createDev)
+	if err != nil {
+		if reason != "" {
+			d.Status.SetConditions(v1alpha2.UserDataUnrendered(reason, err))
+		}
+		return managed.ExternalCreation{}, err
+	}
+	if userdata != nil {
+		if err := devicesclient.CheckUserDataSize(*userdata); err != nil {
+			d.Status.SetConditions(v1alpha2.UserDataUnrendered(v1alpha2.ReasonUserDataTooLarge, err))
 			return managed.ExternalCreation{}, err
 		}
-		createDev.Spec.ForProvider.UserData = &userdata
+	}
+	if reason != "" {
+		d.Status.SetConditions(v1alpha2.UserDataRendered())
+	}
+	fp.UserData = userdata
+
+	// Devices that could never provision report the specific reason, rather
+	// than the API error of the create request. The check is skipped if the
+	// catalog cannot be listed, so that provisioning does not depend on it.
+	if err := devicesclient.CheckProvisionable(e.catalog, *fp); err != nil {
+		if _, ok := err.(*devicesclient.ProvisionError); ok {
+			return managed.ExternalCreation{}, err
+		}
+		e.recorder.Event(d, event.Warning(reasonCatalogUnavailable, errors.Wrap(err, errCheckCatalog)))
 	}
 
 	create := devicesclient.CreateFromDevice(createDev, e.client.GetProjectID(packetclient.CredentialProjectID))
@@ -238,12 +634,23 @@ func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.Ext
 	}
 
 	d.Status.AtProvider.ID = device.ID
+	d.Spec.ForProvider.Facility, d.Spec.ForProvider.Metro = fp.Facility, fp.Metro
+	d.Status.AtProvider.Facility = fp.Facility
+	d.Status.AtProvider.Metro = fp.Metro
+	if userdata != nil {
+		d.Status.AtProvider.UserDataHash = devicesclient.UserDataHash(*userdata)
+	}
 	meta.SetExternalName(d, device.ID)
+
+	// Updating the Device replaces its status with the stored status. It is
+	// restored so that the managed reconciler persists it once Create returns.
+	status := d.Status.DeepCopy()
 	if err := e.kube.Update(ctx, d); err != nil {
 		return managed.ExternalCreation{}, errors.Wrap(err, errManagedUpdateFailed)
 	}
+	d.Status = *status
 
-	return managed.ExternalCreation{ConnectionDetails: devicesclient.GetConnectionDetails(device)}, nil
+	return managed.ExternalCreation{ConnectionDetails: devicesclient.GetConnectionDetails(d, device)}, nil
 }
 
 func (e *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
@@ -259,17 +666,162 @@ func (e *external) Update(ctx context.Context, mg resource.Managed) (managed.Ext
 		return managed.ExternalUpdate{}, errors.Wrap(err, errGetDevice)
 	}
 
+	switch rescued := devicesclient.Rescued(device); {
+	case d.Spec.ForProvider.Rescue != rescued && devicesclient.RescueInProgress(d.Status.AtProvider, time.Now()):
+		return managed.ExternalUpdate{}, nil
+	case d.Spec.ForProvider.Rescue && !rescued:
+		if _, err := e.client.Rescue(meta.GetExternalName(d)); err != nil {
+			return managed.ExternalUpdate{}, errors.Wrap(err, errRescueDevice)
+		}
+		d.Status.AtProvider.RescueAction = &v1alpha2.RescueAction{Action: v1alpha2.RescueActionRescue, RequestedAt: metav1.Now()}
+		e.recorder.Event(d, event.Normal(reasonRescue, "Rebooting the Device into rescue mode"))
+		return managed.ExternalUpdate{}, nil
+	case !d.Spec.ForProvider.Rescue && rescued:
+		if _, err := e.client.Reboot(meta.GetExternalName(d)); err != nil {
+			return managed.ExternalUpdate{}, errors.Wrap(err, errExitRescue)
+		}
+		d.Status.AtProvider.RescueAction = &v1alpha2.RescueAction{Action: v1alpha2.RescueActionExit, RequestedAt: metav1.Now()}
+		e.recorder.Event(d, event.Normal(reasonRescue, "Rebooting the Device out of rescue mode"))
+		return managed.ExternalUpdate{ConnectionDetails: devicesclient.RescueConnectionDetails(device, false)}, nil
+	}
+
 	// NOTE(hasheddan): if the update is for the network type we return early
 	// and do any updates on subsequent reconciles
-	if _, n := devicesclient.IsUpToDate(d, device); !n && d.Spec.ForProvider.NetworkType != nil {
-		_, err := e.client.DeviceToNetworkType(meta.GetExternalName(d), *d.Spec.ForProvider.NetworkType)
+	e.snapshot.Forget(meta.GetExternalName(d))
+	if _, n := devicesclient.IsUpToDate(d, device, nil, nil); !n && d.Spec.ForProvider.NetworkType != nil {
+		return managed.ExternalUpdate{}, e.convertNetworkType(d, device)
+	}
+
+	update := devicesclient.NewUpdateDeviceRequest(d)
+	userdata, reason, err := e.userData(ctx, d)
+	if err != nil {
+		if reason != "" {
+			d.Status.SetConditions(v1alpha2.UserDataUnrendered(reason, err))
+		}
+		return managed.ExternalUpdate{}, err
+	}
+	if reason != "" {
+		d.Status.SetConditions(v1alpha2.UserDataRendered())
+	}
+	reinstall := false
+	if userdata != nil {
+		if err := devicesclient.CheckUserDataSize(*userdata); err != nil {
+			d.Status.SetConditions(v1alpha2.UserDataUnrendered(v1alpha2.ReasonUserDataTooLarge, err))
+			return managed.ExternalUpdate{}, err
+		}
+		update.UserData = userdata
+		policy := d.Spec.ForProvider.UserDataUpdatePolicy
+		reinstall = devicesclient.UserDataHash(*userdata) != d.Status.AtProvider.UserDataHash &&
+			policy != nil && *policy == v1alpha2.UserDataUpdatePolicyReinstall
+	}
+
+	if _, _, err := e.client.Update(meta.GetExternalName(d), update); err != nil {
 		return managed.ExternalUpdate{}, errors.Wrap(err, errUpdateDevice)
 	}
-	_, _, err = e.client.Update(meta.GetExternalName(d), devicesclient.NewUpdateDeviceRequest(d))
 
-	// TODO(displague): use "reinstall" action if userdata changed, after updating the resource
+	if err := e.updateIPAddresses(d, device); err != nil {
+		return managed.ExternalUpdate{}, err
+	}
 
-	return managed.ExternalUpdate{}, errors.Wrap(err, errUpdateDevice)
+	// Changed userdata only takes effect when cloud-init next runs, unless
+	// the operating system is reinstalled.
+	if reinstall {
+		_, err := e.client.Reinstall(meta.GetExternalName(d), devicesclient.NewReinstallRequest(d))
+		return managed.ExternalUpdate{}, errors.Wrap(err, errReinstallDevice)
+	}
+	return managed.ExternalUpdate{}, nil
+}
+
+// convertNetworkType converts the supplied device to the network type of the
+// supplied Device, one step after another. The progress of the conversion is
+// recorded in the status of the Device, so that a conversion that failed or
+// was interrupted resumes at the step it had reached.
+func (e *external) convertNetworkType(d *v1alpha2.Device, device *packngo.Device) error {
+	id := meta.GetExternalName(d)
+	target := *d.Spec.ForProvider.NetworkType
+
+	conv := d.Status.AtProvider.NetworkConversion
+	if conv == nil || conv.To != target {
+		c, err := devicesclient.NewNetworkConversion(device.GetNetworkType(), target)
+		if err != nil {
+			return errors.Wrap(err, errUpdateDevice)
+		}
+		conv = c
+		d.Status.AtProvider.NetworkConversion = conv
+	}
+
+	for i := devicesclient.NextNetworkConversionStep(conv); i >= 0; i = devicesclient.NextNetworkConversionStep(conv) {
+		step := &conv.Steps[i]
+		d.Status.SetConditions(v1alpha2.NetworkTypeConverting(*conv, i))
+		if err := devicesclient.ConvertNetworkStep(e.client, device, target, step.Name); err != nil {
+			step.Phase, step.Message = v1alpha2.NetworkConversionPhaseFailed, err.Error()
+			d.Status.SetConditions(v1alpha2.NetworkTypeConversionFailed(*conv, i, err))
+			return errors.Wrapf(err, errConvertNetworkStepFmt, step.Name)
+		}
+		step.Phase, step.Message = v1alpha2.NetworkConversionPhaseCompleted, ""
+
+		// The ports of the device change with each step.
+		var err error
+		if device, _, err = e.client.Get(id, nil); err != nil {
+			return errors.Wrap(err, errGetDevice)
+		}
+	}
+
+	// A conversion that completed without reaching its network type is
+	// started again on the next reconcile.
+	if observed := device.GetNetworkType(); observed != target {
+		err := errors.Errorf(errNetworkTypeUnchangedFmt, target, observed)
+		d.Status.SetConditions(v1alpha2.NetworkTypeConversionFailed(*conv, len(conv.Steps)-1, err))
+		d.Status.AtProvider.NetworkConversion = nil
+		return err
+	}
+	return nil
+}
+
+// updateIPAddresses assigns the IP addresses of the supplied Device that are
+// not assigned to the supplied device, drawing them from their reservations,
+// and then unassigns those that were removed from the Device. Management
+// addresses are left assigned.
+func (e *external) updateIPAddresses(d *v1alpha2.Device, device *packngo.Device) error {
+	id := meta.GetExternalName(d)
+	reservations, err := devicesclient.GetIPReservations(e.client, d.Spec.ForProvider.IPAddresses)
+	if err != nil {
+		return err
+	}
+	add, remove := devicesclient.IPAddressChanges(d, device, reservations)
+	for _, ip := range add {
+		address, err := e.availableAddress(ip)
+		if err != nil {
+			return err
+		}
+		if _, _, err := e.client.AssignIPAddress(id, address); err != nil {
+			return errors.Wrapf(err, errAssignIPAddressFmt, address)
+		}
+	}
+	for _, a := range remove {
+		if _, err := e.client.UnassignIPAddress(a.ID); resource.Ignore(packetclient.IsNotFound, err) != nil {
+			return errors.Wrapf(err, errUnassignIPAddressFmt, a.Address)
+		}
+	}
+	return nil
+}
+
+// availableAddress returns the first address available in the reservations of
+// the supplied IP address.
+func (e *external) availableAddress(ip v1alpha2.IPAddress) (string, error) {
+	if len(ip.Reservations) == 0 {
+		return "", errors.New(errIPAddressNoReservations)
+	}
+	for _, r := range ip.Reservations {
+		available, _, err := e.client.AvailableAddresses(r, devicesclient.DefaultCIDR(ip))
+		if err != nil {
+			return "", errors.Wrapf(err, errAvailableAddressesFmt, r)
+		}
+		if len(available) > 0 {
+			return available[0], nil
+		}
+	}
+	return "", errors.Errorf(errNoAvailableAddressFmt, ip.Reservations)
 }
 
 func (e *external) Delete(ctx context.Context, mg resource.Managed) error {
@@ -279,6 +831,40 @@ func (e *external) Delete(ctx context.Context, mg resource.Managed) error {
 	}
 	d.SetConditions(xpv1.Deleting())
 
-	_, err := e.client.Delete(meta.GetExternalName(d), false)
-	return errors.Wrap(resource.Ignore(packetclient.IsNotFound, err), errDeleteDevice)
+	id := meta.GetExternalName(d)
+	e.snapshot.Forget(id)
+
+	// A Device that is deprovisioning is not deleted again; it is reported
+	// as gone by Observe.
+	if d.Status.AtProvider.State == v1alpha2.StateDeprovisioning {
+		return nil
+	}
+
+	fp := d.Spec.ForProvider
+	if d.Status.AtProvider.Locked {
+		if !fp.UnlockBeforeDelete {
+			err := errors.New(errDeviceLocked)
+			e.recorder.Event(d, event.Warning(reasonDeletionBlocked, err))
+			return err
+		}
+		unlocked := false
+		if _, _, err := e.client.Update(id, &packngo.DeviceUpdateRequest{Locked: &unlocked}); err != nil {
+			return errors.Wrap(resource.Ignore(packetclient.IsNotFound, err), errUnlockDevice)
+		}
+	}
+
+	_, err := e.client.Delete(id, fp.ForceDelete)
+	if resource.Ignore(packetclient.IsNotFound, err) == nil {
+		return nil
+	}
+	if !fp.ForceDelete && isProvisioning(d.Status.AtProvider.State) {
+		e.recorder.Event(d, event.Warning(reasonDeletionBlocked, errors.Wrapf(err, errDeviceProvisioningFmt, d.Status.AtProvider.State)))
+	}
+	return errors.Wrap(err, errDeleteDevice)
+}
+
+// isProvisioning returns true if a Device in the supplied state has not
+// finished provisioning.
+func isProvisioning(state string) bool {
+	return state == v1alpha2.StateQueued || state == v1alpha2.StateProvisioning
 }
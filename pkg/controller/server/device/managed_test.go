@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/packethost/packngo"
@@ -29,7 +30,10 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apiresource "k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 
 	"github.com/packethost/crossplane-provider-equinix-metal/apis/server/v1alpha2"
 	packetv1beta1 "github.com/packethost/crossplane-provider-equinix-metal/apis/v1beta1"
@@ -39,6 +43,7 @@ import (
 	packettest "github.com/packethost/crossplane-provider-equinix-metal/pkg/test"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
 	"github.com/crossplane/crossplane-runtime/pkg/meta"
 	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
 	"github.com/crossplane/crossplane-runtime/pkg/resource"
@@ -52,7 +57,7 @@ const (
 	providerName       = "cool-equinix-metal"
 	providerSecretName = "cool-equinix-metal-secret"
 	providerSecretKey  = "credentials"
-	providerSecretData = "{\"definitely\":\"json\"}"
+	providerSecretData = "{\"apiKey\":\"definitely-json\"}"
 
 	connectionSecretName = "cool-connection-secret"
 )
@@ -142,6 +147,11 @@ var (
 	}
 )
 
+var (
+	deletionTime = metav1.Now()
+	expiringTime = metav1.NewTime(deletionTime.Add(30*time.Minute - time.Second))
+)
+
 type strange struct {
 	resource.Managed
 }
@@ -166,10 +176,134 @@ func withID(d string) deviceModifier {
 	return func(i *v1alpha2.Device) { i.Status.AtProvider.ID = d }
 }
 
+func withMetro(m string) deviceModifier {
+	return func(i *v1alpha2.Device) { i.Status.AtProvider.Metro = m }
+}
+
 func withNetworkType(d *string) deviceModifier {
 	return func(i *v1alpha2.Device) { i.Spec.ForProvider.NetworkType = d }
 }
 
+// networkConversion returns the pending conversion of a device from the
+// supplied network type to the default network type.
+func networkConversion(from string) v1alpha2.NetworkConversion {
+	c, _ := devicesclient.NewNetworkConversion(from, networkType)
+	return *c
+}
+
+func withNetworkConversion(from string, phases ...string) deviceModifier {
+	return func(i *v1alpha2.Device) {
+		c := networkConversion(from)
+		for n, p := range phases {
+			c.Steps[n].Phase = p
+		}
+		i.Status.AtProvider.NetworkConversion = &c
+	}
+}
+
+// mockNetworkTypes returns a MockGet function that returns a device of each of
+// the supplied network types in turn, and then of the last of them.
+func mockNetworkTypes(types ...string) func(string, *packngo.GetOptions) (*packngo.Device, *packngo.Response, error) {
+	calls := 0
+	return func(deviceID string, getOpt *packngo.GetOptions) (*packngo.Device, *packngo.Response, error) {
+		t := types[len(types)-1]
+		if calls < len(types) {
+			t = types[calls]
+		}
+		calls++
+		d := &packngo.Device{}
+		d.Network = mockNetworkTypeConfigs[t].Network
+		d.NetworkPorts = mockNetworkTypeConfigs[t].NetworkPorts
+		return d, nil, nil
+	}
+}
+
+func withUserDataTemplate() deviceModifier {
+	return func(i *v1alpha2.Device) {
+		i.Spec.ForProvider.UserDataRef = &v1alpha2.DataKeySelector{
+			NamespacedName: v1alpha2.NamespacedName{Namespace: namespace, Name: "userdata"},
+			Kind:           "ConfigMap",
+		}
+		i.Spec.ForProvider.UserDataTemplate = &v1alpha2.UserDataTemplate{
+			Resources: []v1alpha2.TemplateResourceReference{{
+				Name:        "vlan",
+				ResourceRef: xpv1.TypedReference{APIVersion: "vlan.metal.equinix.com/v1alpha1", Kind: "VirtualNetwork", Name: "vlan"},
+			}},
+		}
+	}
+}
+
+func withSpecMetro(m string) deviceModifier {
+	return func(i *v1alpha2.Device) { i.Spec.ForProvider.Metro = m }
+}
+
+func withUserDataHash(userdata string) deviceModifier {
+	return func(i *v1alpha2.Device) { i.Status.AtProvider.UserDataHash = devicesclient.UserDataHash(userdata) }
+}
+
+func withDeletionTimestamp() deviceModifier {
+	return func(i *v1alpha2.Device) { i.SetDeletionTimestamp(&deletionTime) }
+}
+
+func withTerminationTime(t metav1.Time) deviceModifier {
+	return func(i *v1alpha2.Device) { i.Spec.ForProvider.TerminationTime = &t }
+}
+
+func withRescue(rescue, rescued bool) deviceModifier {
+	return func(i *v1alpha2.Device) {
+		i.Spec.ForProvider.Rescue = rescue
+		i.Status.AtProvider.Rescued = rescued
+	}
+}
+
+func withRescueAction(action string, at time.Time) deviceModifier {
+	return func(i *v1alpha2.Device) {
+		i.Status.AtProvider.RescueAction = &v1alpha2.RescueAction{Action: action, RequestedAt: metav1.NewTime(at)}
+	}
+}
+
+// invalidStorage partitions a disk with two partitions numbered 1.
+var invalidStorage = &v1alpha2.Storage{Disks: []v1alpha2.Disk{{
+	Device:     "/dev/sda",
+	Partitions: []v1alpha2.Partition{{Label: "BIOS", Number: 1, Size: "4096"}, {Label: "ROOT", Number: 1, Size: "0"}},
+}}}
+
+func withStorage(s *v1alpha2.Storage) deviceModifier {
+	return func(i *v1alpha2.Device) { i.Spec.ForProvider.Storage = s }
+}
+
+func withLocked() deviceModifier {
+	return func(i *v1alpha2.Device) { i.Status.AtProvider.Locked = true }
+}
+
+func withDeletionOptions(unlock, force bool) deviceModifier {
+	return func(i *v1alpha2.Device) {
+		i.Spec.ForProvider.UnlockBeforeDelete = unlock
+		i.Spec.ForProvider.ForceDelete = force
+	}
+}
+
+func withUserDataUpdatePolicy(p string) deviceModifier {
+	return func(i *v1alpha2.Device) { i.Spec.ForProvider.UserDataUpdatePolicy = &p }
+}
+
+// userdataTemplateGet returns the templated userdata ConfigMap, and a
+// VirtualNetwork with the supplied VXLAN.
+func userdataTemplateGet(vxlan interface{}) test.MockGetFn {
+	return func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+		switch o := obj.(type) {
+		case *corev1.ConfigMap:
+			o.Data = map[string]string{userdataMapKey: "vlan {{ .Resources.vlan.status.atProvider.vxlan }} in {{ .Device.spec.forProvider.metro }}"}
+		case *unstructured.Unstructured:
+			if vxlan == nil {
+				return errorBoom
+			}
+			o.Object["status"] = map[string]interface{}{"atProvider": map[string]interface{}{"vxlan": vxlan}}
+		}
+		return nil
+	}
+}
+
 type initializerParams struct {
 	hostname, billingCycle, userdata, ipxeScriptURL string
 	locked                                          bool
@@ -219,6 +353,33 @@ func projectIDFromCredentials(_ string) string {
 	return "id-from-credentials"
 }
 
+func noDefault(s string) string {
+	return s
+}
+
+// catalog returns a MockCatalog that offers every plan and operating system
+// in the supplied metros, with capacity.
+func catalog(metros ...string) *fake.MockCatalog {
+	available := make([]packngo.Metro, len(metros))
+	for i, m := range metros {
+		available[i] = packngo.Metro{Code: m}
+	}
+	return &fake.MockCatalog{
+		MockPlan: func(slug string) (*packngo.Plan, error) {
+			return &packngo.Plan{Slug: slug, AvailableInMetros: available}, nil
+		},
+		MockOperatingSystem: func(slug string) (*packngo.OS, error) {
+			return &packngo.OS{Slug: slug}, nil
+		},
+		MockMetro: func(code string) (*packngo.Metro, error) {
+			return &packngo.Metro{Code: code}, nil
+		},
+		MockCapacityLevel: func(_, _, _ string) (string, error) {
+			return "normal", nil
+		},
+	}
+}
+
 var _ managed.ExternalClient = &external{}
 var _ managed.ExternalConnecter = &connecter{}
 
@@ -264,8 +425,7 @@ func TestConnect(t *testing.T) {
 		errGetCredentialsSecret    = "cannot get credentials secret"
 		errGetCredentials          = "cannot get credentials"
 		errGetProviderConfigSecret = "cannot get ProviderConfig Secret"
-		errGetProviderConfigUsage  = "cannot apply ProviderConfigUsage"
-		errGetObject               = "cannot get object"
+		errGetProviderConfig       = "cannot get ProviderConfig"
 	)
 
 	cases := map[string]struct {
@@ -288,7 +448,7 @@ func TestConnect(t *testing.T) {
 					MockGet:    test.NewMockGetFn(nil),
 					MockUpdate: test.NewMockUpdateFn(nil),
 				}, &packetv1beta1.ProviderConfigUsage{}),
-				newClientFn: func(_ context.Context, _ *clients.Credentials) (devicesclient.ClientWithDefaults, error) {
+				newClientFn: func(_ context.Context, _ *clients.Client) (devicesclient.ClientWithDefaults, error) {
 					return nil, nil
 				},
 			},
@@ -317,7 +477,7 @@ func TestConnect(t *testing.T) {
 			},
 			args: args{ctx: context.Background(), mg: device()},
 			want: want{err: errors.Wrap(
-				errors.Wrap(errors.Wrap(errorBoom, errGetObject), errGetProviderConfigUsage), errGetProviderConfigSecret,
+				errors.Wrap(errorBoom, errGetProviderConfig), errGetProviderConfigSecret,
 			)},
 		},
 		"FailedToGetProviderSecret": {
@@ -379,7 +539,7 @@ func TestConnect(t *testing.T) {
 					MockGet:    test.NewMockGetFn(nil),
 					MockUpdate: test.NewMockUpdateFn(nil),
 				}, &packetv1beta1.ProviderConfigUsage{}),
-				newClientFn: func(_ context.Context, _ *clients.Credentials) (devicesclient.ClientWithDefaults, error) {
+				newClientFn: func(_ context.Context, _ *clients.Client) (devicesclient.ClientWithDefaults, error) {
 					return nil, errorBoom
 				},
 			},
@@ -423,6 +583,7 @@ func TestObserve(t *testing.T) {
 				client: &fake.MockClient{
 					MockGet: func(deviceID string, getOpt *packngo.GetOptions) (*packngo.Device, *packngo.Response, error) {
 						d := &packngo.Device{
+							Tags:         clients.ManagedTags(device()),
 							State:        v1alpha2.StateActive,
 							ProvisionPer: float32(100),
 							AlwaysPXE:    *alwaysPXE,
@@ -491,6 +652,7 @@ func TestObserve(t *testing.T) {
 				client: &fake.MockClient{
 					MockGet: func(deviceID string, getOpt *packngo.GetOptions) (*packngo.Device, *packngo.Response, error) {
 						d := &packngo.Device{
+							Tags:         clients.ManagedTags(device()),
 							State:        v1alpha2.StateProvisioning,
 							ProvisionPer: float32(50),
 							AlwaysPXE:    *alwaysPXE,
@@ -529,6 +691,7 @@ func TestObserve(t *testing.T) {
 				client: &fake.MockClient{
 					MockGet: func(deviceID string, getOpt *packngo.GetOptions) (*packngo.Device, *packngo.Response, error) {
 						d := &packngo.Device{
+							Tags:         clients.ManagedTags(device()),
 							State:        v1alpha2.StateQueued,
 							ProvisionPer: float32(50),
 							AlwaysPXE:    *alwaysPXE,
@@ -556,6 +719,220 @@ func TestObserve(t *testing.T) {
 				},
 			},
 		},
+		"ExpiredDeviceDeleted": {
+			client: &external{
+				kube: &test.MockClient{
+					MockDelete: test.NewMockDeleteFn(nil),
+				},
+				recorder: event.NewNopRecorder(),
+			},
+			args: args{
+				ctx: context.Background(),
+				mg:  device(withTerminationTime(deletionTime)),
+			},
+			want: want{
+				mg:          device(withTerminationTime(deletionTime)),
+				observation: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+			},
+		},
+		"ConvertedNetworkType": {
+			client: &external{
+				client: &fake.MockClient{
+					MockGet: func(deviceID string, getOpt *packngo.GetOptions) (*packngo.Device, *packngo.Response, error) {
+						return &packngo.Device{State: v1alpha2.StateActive, AlwaysPXE: *alwaysPXE, Tags: clients.ManagedTags(device())}, nil, nil
+					},
+				},
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+			},
+			args: args{
+				ctx: context.Background(),
+				mg: device(
+					withNetworkType(&networkType),
+					withNetworkConversion(packngo.NetworkTypeHybrid, v1alpha2.NetworkConversionPhaseCompleted, v1alpha2.NetworkConversionPhaseCompleted),
+				),
+			},
+			want: want{
+				mg: device(
+					withInitializerParams(initializerParams{}),
+					withConditions(xpv1.Available(), v1alpha2.NetworkTypeConverted(networkConversion(packngo.NetworkTypeHybrid))),
+					withProvisionPer(float32(0)),
+					withNetworkType(&networkType),
+					withState(v1alpha2.StateActive)),
+				observation: managed.ExternalObservation{
+					ResourceExists:    true,
+					ResourceUpToDate:  true,
+					ConnectionDetails: managed.ConnectionDetails{},
+				},
+			},
+		},
+		"RescuedDevice": {
+			client: &external{
+				client: &fake.MockClient{
+					MockGet: func(deviceID string, getOpt *packngo.GetOptions) (*packngo.Device, *packngo.Response, error) {
+						return &packngo.Device{State: v1alpha2.StateActive, Hostname: "rescue", OS: &packngo.OS{Slug: devicesclient.RescueOS}, RootPassword: "temporary", Tags: clients.ManagedTags(device())}, nil, nil
+					},
+				},
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+			},
+			args: args{
+				ctx: context.Background(),
+				mg:  device(withRescue(true, false), withRescueAction(v1alpha2.RescueActionRescue, time.Now()), withInitializerParams(initializerParams{hostname: "drifted"})),
+			},
+			want: want{
+				mg: device(
+					withRescue(true, true),
+					withInitializerParams(initializerParams{hostname: "drifted"}),
+					withConditions(xpv1.Available()),
+					withProvisionPer(float32(0)),
+					withNetworkType(&networkType),
+					withState(v1alpha2.StateActive)),
+				observation: managed.ExternalObservation{
+					ResourceExists:   true,
+					ResourceUpToDate: true,
+					ConnectionDetails: managed.ConnectionDetails{
+						devicesclient.ConnectionRescueKey:         []byte("true"),
+						devicesclient.ConnectionRescueUserKey:     []byte("root"),
+						devicesclient.ConnectionRescuePasswordKey: []byte("temporary"),
+					},
+				},
+			},
+		},
+		"LeftRescue": {
+			client: &external{
+				client: &fake.MockClient{
+					MockGet: func(deviceID string, getOpt *packngo.GetOptions) (*packngo.Device, *packngo.Response, error) {
+						return &packngo.Device{State: v1alpha2.StateActive, Tags: clients.ManagedTags(device())}, nil, nil
+					},
+				},
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+			},
+			args: args{
+				ctx: context.Background(),
+				mg:  device(withRescue(true, true), withInitializerParams(initializerParams{})),
+			},
+			want: want{
+				mg: device(
+					withRescue(true, false),
+					withInitializerParams(initializerParams{}),
+					withConditions(xpv1.Available()),
+					withProvisionPer(float32(0)),
+					withNetworkType(&networkType),
+					withState(v1alpha2.StateActive)),
+				observation: managed.ExternalObservation{
+					ResourceExists:    true,
+					ResourceUpToDate:  false,
+					ConnectionDetails: managed.ConnectionDetails{},
+				},
+			},
+		},
+		"EnteringRescue": {
+			client: &external{
+				client: &fake.MockClient{
+					MockGet: func(deviceID string, getOpt *packngo.GetOptions) (*packngo.Device, *packngo.Response, error) {
+						return &packngo.Device{State: v1alpha2.StateActive, Tags: clients.ManagedTags(device())}, nil, nil
+					},
+				},
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+			},
+			args: args{
+				ctx: context.Background(),
+				mg:  device(withRescue(true, false), withRescueAction(v1alpha2.RescueActionRescue, time.Now()), withInitializerParams(initializerParams{})),
+			},
+			want: want{
+				mg: device(
+					withRescue(true, false),
+					withRescueAction(v1alpha2.RescueActionRescue, time.Now()),
+					withInitializerParams(initializerParams{}),
+					withConditions(xpv1.Available()),
+					withProvisionPer(float32(0)),
+					withNetworkType(&networkType),
+					withState(v1alpha2.StateActive)),
+				observation: managed.ExternalObservation{
+					ResourceExists:    true,
+					ResourceUpToDate:  true,
+					ConnectionDetails: managed.ConnectionDetails{},
+				},
+			},
+		},
+		"RescueTimedOut": {
+			client: &external{
+				client: &fake.MockClient{
+					MockGet: func(deviceID string, getOpt *packngo.GetOptions) (*packngo.Device, *packngo.Response, error) {
+						return &packngo.Device{State: v1alpha2.StateActive, Tags: clients.ManagedTags(device())}, nil, nil
+					},
+				},
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+			},
+			args: args{
+				ctx: context.Background(),
+				mg:  device(withRescue(true, false), withRescueAction(v1alpha2.RescueActionRescue, time.Now().Add(-devicesclient.RescueTimeout)), withInitializerParams(initializerParams{})),
+			},
+			want: want{
+				mg: device(
+					withRescue(true, false),
+					withRescueAction(v1alpha2.RescueActionRescue, time.Now().Add(-devicesclient.RescueTimeout)),
+					withInitializerParams(initializerParams{}),
+					withConditions(xpv1.Available()),
+					withProvisionPer(float32(0)),
+					withNetworkType(&networkType),
+					withState(v1alpha2.StateActive)),
+				observation: managed.ExternalObservation{
+					ResourceExists:    true,
+					ResourceUpToDate:  false,
+					ConnectionDetails: managed.ConnectionDetails{},
+				},
+			},
+		},
+		"ExpiringDevice": {
+			client: &external{
+				client: &fake.MockClient{
+					MockGet: func(deviceID string, getOpt *packngo.GetOptions) (*packngo.Device, *packngo.Response, error) {
+						return &packngo.Device{State: v1alpha2.StateActive, AlwaysPXE: *alwaysPXE, Tags: clients.ManagedTags(device())}, nil, nil
+					},
+				},
+				kube:     &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+				recorder: event.NewNopRecorder(),
+			},
+			args: args{
+				ctx: context.Background(),
+				mg:  device(withTerminationTime(expiringTime)),
+			},
+			want: want{
+				mg: device(
+					withTerminationTime(expiringTime),
+					withInitializerParams(initializerParams{}),
+					withConditions(xpv1.Available(), v1alpha2.Expiring(expiringTime)),
+					withProvisionPer(float32(0)),
+					withNetworkType(&networkType),
+					withState(v1alpha2.StateActive),
+					func(i *v1alpha2.Device) {
+						i.Status.AtProvider.TerminationTime = &expiringTime
+						i.Status.AtProvider.RemainingLifetime = "29m"
+					}),
+				observation: managed.ExternalObservation{
+					ResourceExists:    true,
+					ResourceUpToDate:  true,
+					ConnectionDetails: managed.ConnectionDetails{},
+				},
+			},
+		},
+		"DeletedDeviceDeprovisioning": {
+			client: &external{
+				client: &fake.MockClient{
+					MockGet: func(deviceID string, getOpt *packngo.GetOptions) (*packngo.Device, *packngo.Response, error) {
+						return &packngo.Device{State: v1alpha2.StateDeprovisioning}, nil, nil
+					},
+				},
+			},
+			args: args{
+				ctx: context.Background(),
+				mg:  device(withDeletionTimestamp()),
+			},
+			want: want{
+				mg:          device(withDeletionTimestamp()),
+				observation: managed.ExternalObservation{ResourceExists: false},
+			},
+		},
 		"ObservedDeviceDoesNotExist": {
 			client: &external{client: &fake.MockClient{
 				MockGet: func(deviceID string, getOpt *packngo.GetOptions) (*packngo.Device, *packngo.Response, error) {
@@ -615,7 +992,7 @@ func TestObserve(t *testing.T) {
 				t.Errorf("tc.client.Observe(): -want error, +got error:\n%s", diff)
 			}
 
-			if diff := cmp.Diff(tc.want.mg, tc.args.mg, test.EquateConditions(), packettest.EquateQuantities()); diff != "" {
+			if diff := cmp.Diff(tc.want.mg, tc.args.mg, test.EquateConditions(), packettest.EquateQuantities(), packettest.EquateApproxTimes(time.Minute)); diff != "" {
 				t.Errorf("resource.Managed: -want, +got:\n%s", diff)
 			}
 		})
@@ -640,8 +1017,11 @@ func TestCreate(t *testing.T) {
 	}{
 		"CreatedInstance": {
 			client: &external{
+				catalog: catalog(),
 				client: &fake.MockClient{
-					MockGetProjectID: projectIDFromCredentials,
+					MockGetProjectID:  projectIDFromCredentials,
+					MockGetMetro:      noDefault,
+					MockGetFacilityID: noDefault,
 					MockCreate: func(createRequest *packngo.DeviceCreateRequest) (*packngo.Device, *packngo.Response, error) {
 						d := &packngo.Device{
 							ID: deviceName,
@@ -668,6 +1048,174 @@ func TestCreate(t *testing.T) {
 				},
 			},
 		},
+		"CreatedInstanceInDefaultMetro": {
+			client: &external{
+				catalog: catalog("sv"),
+				client: &fake.MockClient{
+					MockGetProjectID:  projectIDFromCredentials,
+					MockGetMetro:      func(_ string) string { return "sv" },
+					MockGetFacilityID: noDefault,
+					MockCreate: func(createRequest *packngo.DeviceCreateRequest) (*packngo.Device, *packngo.Response, error) {
+						if createRequest.Metro != "sv" || len(createRequest.Facility) != 0 {
+							return nil, nil, errorBoom
+						}
+						return &packngo.Device{ID: deviceName}, nil, nil
+					},
+				},
+				kube: &test.MockClient{
+					MockUpdate: test.NewMockUpdateFn(nil, func(obj client.Object) error {
+						// The API server returns the stored status.
+						obj.(*v1alpha2.Device).Status = v1alpha2.DeviceStatus{}
+						return nil
+					}),
+				},
+			},
+			args: args{
+				ctx: context.Background(),
+				mg:  device(),
+			},
+			want: want{
+				mg: device(
+					withConditions(xpv1.Creating()),
+					withID(deviceName),
+					withSpecMetro("sv"),
+					withMetro("sv"),
+				),
+				creation: managed.ExternalCreation{
+					ConnectionDetails: managed.ConnectionDetails{},
+				},
+			},
+		},
+		"CreatedInstanceWithTemplatedUserData": {
+			client: &external{
+				catalog: catalog("sv"),
+				client: &fake.MockClient{
+					MockGetProjectID:  projectIDFromCredentials,
+					MockGetMetro:      func(_ string) string { return "sv" },
+					MockGetFacilityID: noDefault,
+					MockCreate: func(createRequest *packngo.DeviceCreateRequest) (*packngo.Device, *packngo.Response, error) {
+						if createRequest.UserData != "vlan 1000 in sv" {
+							return nil, nil, errorBoom
+						}
+						return &packngo.Device{ID: deviceName}, nil, nil
+					},
+				},
+				kube: &test.MockClient{
+					MockGet:    userdataTemplateGet(int64(1000)),
+					MockUpdate: test.NewMockUpdateFn(nil),
+				},
+			},
+			args: args{
+				ctx: context.Background(),
+				mg:  device(withUserDataTemplate()),
+			},
+			want: want{
+				mg: device(
+					withUserDataTemplate(),
+					withConditions(xpv1.Creating(), v1alpha2.UserDataRendered()),
+					withID(deviceName),
+					withSpecMetro("sv"),
+					withMetro("sv"),
+					withUserDataHash("vlan 1000 in sv"),
+				),
+				creation: managed.ExternalCreation{
+					ConnectionDetails: managed.ConnectionDetails{},
+				},
+			},
+		},
+		"TemplatedUserDataReferenceUnresolved": {
+			client: &external{
+				catalog: catalog("sv"),
+				client: &fake.MockClient{
+					MockGetProjectID:  projectIDFromCredentials,
+					MockGetMetro:      func(_ string) string { return "sv" },
+					MockGetFacilityID: noDefault,
+				},
+				kube: &test.MockClient{
+					MockGet: userdataTemplateGet(nil),
+				},
+			},
+			args: args{
+				ctx: context.Background(),
+				mg:  device(withUserDataTemplate()),
+			},
+			want: want{
+				mg: device(
+					withUserDataTemplate(),
+					withConditions(xpv1.Creating(), v1alpha2.UserDataUnrendered(v1alpha2.ReasonTemplateReferenceUnresolved,
+						errors.Wrapf(errorBoom, errGetTemplateResourceFmt, "vlan"))),
+				),
+				err: errors.Wrapf(errorBoom, errGetTemplateResourceFmt, "vlan"),
+			},
+		},
+		"InvalidStorage": {
+			client: &external{},
+			args: args{
+				ctx: context.Background(),
+				mg:  device(withStorage(invalidStorage)),
+			},
+			want: want{
+				mg: device(withStorage(invalidStorage), withConditions(xpv1.Creating())),
+				err: errors.Wrap(field.ErrorList{
+					field.Duplicate(field.NewPath("spec", "forProvider", "storage", "disks").Index(0).Child("partitions").Index(1).Child("number"), 1),
+				}.ToAggregate(), errInvalidStorage),
+			},
+		},
+		"CatalogUnavailable": {
+			client: &external{
+				catalog: &fake.MockCatalog{
+					MockPlan: func(slug string) (*packngo.Plan, error) {
+						return nil, errorBoom
+					},
+				},
+				client: &fake.MockClient{
+					MockGetProjectID:  projectIDFromCredentials,
+					MockGetMetro:      func(_ string) string { return "sv" },
+					MockGetFacilityID: noDefault,
+					MockCreate: func(createRequest *packngo.DeviceCreateRequest) (*packngo.Device, *packngo.Response, error) {
+						return &packngo.Device{ID: deviceName}, nil, nil
+					},
+				},
+				kube:     &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+				recorder: event.NewNopRecorder(),
+			},
+			args: args{
+				ctx: context.Background(),
+				mg:  device(),
+			},
+			want: want{
+				mg: device(
+					withConditions(xpv1.Creating()),
+					withID(deviceName),
+					withSpecMetro("sv"),
+					withMetro("sv"),
+				),
+				creation: managed.ExternalCreation{
+					ConnectionDetails: managed.ConnectionDetails{},
+				},
+			},
+		},
+		"PlanNotOfferedInDefaultMetro": {
+			client: &external{
+				catalog: catalog("da"),
+				client: &fake.MockClient{
+					MockGetProjectID:  projectIDFromCredentials,
+					MockGetMetro:      func(_ string) string { return "sv" },
+					MockGetFacilityID: noDefault,
+				},
+			},
+			args: args{
+				ctx: context.Background(),
+				mg:  device(),
+			},
+			want: want{
+				mg: device(withConditions(xpv1.Creating())),
+				err: &devicesclient.ProvisionError{
+					Reason:  v1alpha2.ReasonLocationUnavailable,
+					Message: `plan "" is not offered in metro "sv"`,
+				},
+			},
+		},
 		"NotDevice": {
 			client: &external{},
 			args: args{
@@ -680,8 +1228,10 @@ func TestCreate(t *testing.T) {
 			},
 		},
 		"FailedToCreateDevice": {
-			client: &external{client: &fake.MockClient{
-				MockGetProjectID: projectIDFromCredentials,
+			client: &external{catalog: catalog(), client: &fake.MockClient{
+				MockGetProjectID:  projectIDFromCredentials,
+				MockGetMetro:      noDefault,
+				MockGetFacilityID: noDefault,
 				MockCreate: func(createRequest *packngo.DeviceCreateRequest) (*packngo.Device, *packngo.Response, error) {
 					return nil, nil, errorBoom
 				},
@@ -752,16 +1302,31 @@ func TestUpdate(t *testing.T) {
 		},
 		"UpdatedInstanceNetworkType": {
 			client: &external{client: &fake.MockClient{
-				MockGet: func(deviceID string, getOpt *packngo.GetOptions) (*packngo.Device, *packngo.Response, error) {
-					d := &packngo.Device{}
-					target := packngo.NetworkTypeHybrid
-					d.Network = mockNetworkTypeConfigs[target].Network
-					d.NetworkPorts = mockNetworkTypeConfigs[target].NetworkPorts
-
-					return d, nil, nil
+				MockGet: mockNetworkTypes(packngo.NetworkTypeHybrid, networkType),
+				MockDisbond: func(portID string, bulkDisable bool) (*packngo.Port, *packngo.Response, error) {
+					if portID != "bond0" || !bulkDisable {
+						return nil, nil, errorBoom
+					}
+					return nil, nil, nil
 				},
-				MockDeviceToNetworkType: func(deviceID string, networkType string) (*packngo.Device, error) {
-					return nil, nil
+			}},
+			args: args{
+				ctx: context.Background(),
+				mg:  device(withNetworkType(&networkType)),
+			},
+			want: want{
+				mg: device(
+					withNetworkType(&networkType),
+					withNetworkConversion(packngo.NetworkTypeHybrid, v1alpha2.NetworkConversionPhaseCompleted, v1alpha2.NetworkConversionPhaseCompleted),
+					withConditions(v1alpha2.NetworkTypeConverting(networkConversion(packngo.NetworkTypeHybrid), 1)),
+				),
+			},
+		},
+		"FailedToConvertInstanceNetworkType": {
+			client: &external{client: &fake.MockClient{
+				MockGet: mockNetworkTypes(packngo.NetworkTypeHybrid),
+				MockDisbond: func(portID string, bulkDisable bool) (*packngo.Port, *packngo.Response, error) {
+					return nil, nil, errorBoom
 				},
 			}},
 			args: args{
@@ -769,7 +1334,40 @@ func TestUpdate(t *testing.T) {
 				mg:  device(withNetworkType(&networkType)),
 			},
 			want: want{
-				mg: device(withNetworkType(&networkType), withConditions()),
+				mg: device(
+					withNetworkType(&networkType),
+					withNetworkConversion(packngo.NetworkTypeHybrid, v1alpha2.NetworkConversionPhaseCompleted, v1alpha2.NetworkConversionPhaseFailed),
+					func(i *v1alpha2.Device) {
+						i.Status.AtProvider.NetworkConversion.Steps[1].Message = "cannot disbond port bond0: boom"
+					},
+					withConditions(v1alpha2.NetworkTypeConversionFailed(networkConversion(packngo.NetworkTypeHybrid), 1, errors.Wrap(errorBoom, "cannot disbond port bond0"))),
+				),
+				err: errors.Wrap(errors.Wrap(errorBoom, "cannot disbond port bond0"), fmt.Sprintf(errConvertNetworkStepFmt, v1alpha2.NetworkConversionStepDisbondPorts)),
+			},
+		},
+		"ResumedInstanceNetworkTypeConversion": {
+			client: &external{client: &fake.MockClient{
+				MockGet: mockNetworkTypes(packngo.NetworkTypeHybrid, networkType),
+				MockConvertToLayerTwo: func(portID string) (*packngo.Port, *packngo.Response, error) {
+					return nil, nil, errorBoom
+				},
+				MockDisbond: func(portID string, bulkDisable bool) (*packngo.Port, *packngo.Response, error) {
+					return nil, nil, nil
+				},
+			}},
+			args: args{
+				ctx: context.Background(),
+				mg: device(
+					withNetworkType(&networkType),
+					withNetworkConversion(packngo.NetworkTypeL3, v1alpha2.NetworkConversionPhaseCompleted, v1alpha2.NetworkConversionPhaseFailed),
+				),
+			},
+			want: want{
+				mg: device(
+					withNetworkType(&networkType),
+					withNetworkConversion(packngo.NetworkTypeL3, v1alpha2.NetworkConversionPhaseCompleted, v1alpha2.NetworkConversionPhaseCompleted),
+					withConditions(v1alpha2.NetworkTypeConverting(networkConversion(packngo.NetworkTypeL3), 1)),
+				),
 			},
 		},
 		"UpdatedInstance": {
@@ -793,6 +1391,153 @@ func TestUpdate(t *testing.T) {
 				mg: device(withConditions()),
 			},
 		},
+		"AssignedAndUnassignedIPAddresses": {
+			client: &external{client: &fake.MockClient{
+				MockUpdate: func(deviceID string, createRequest *packngo.DeviceUpdateRequest) (*packngo.Device, *packngo.Response, error) {
+					return &packngo.Device{}, nil, nil
+				},
+				MockGet: func(deviceID string, getOpt *packngo.GetOptions) (*packngo.Device, *packngo.Response, error) {
+					return &packngo.Device{Network: []*packngo.IPAddressAssignment{
+						{IpAddressCommon: packngo.IpAddressCommon{ID: "mgmt", AddressFamily: 4, Public: true, CIDR: 31, Management: true}},
+						{IpAddressCommon: packngo.IpAddressCommon{ID: "removed", AddressFamily: 6, Public: true, CIDR: 64}},
+					}}, nil, nil
+				},
+				MockAvailableAddresses: func(reservationID string, cidr int) ([]string, *packngo.Response, error) {
+					if reservationID != "elastic" || cidr != 32 {
+						return nil, nil, errorBoom
+					}
+					return []string{"192.0.2.1/32"}, nil, nil
+				},
+				MockGetIPReservation: func(reservationID string) (*packngo.IPAddressReservation, *packngo.Response, error) {
+					return &packngo.IPAddressReservation{IpAddressCommon: packngo.IpAddressCommon{Network: "192.0.2.0", CIDR: 29}}, nil, nil
+				},
+				MockAssignIPAddress: func(deviceID, address string) (*packngo.IPAddressAssignment, *packngo.Response, error) {
+					if address != "192.0.2.1/32" {
+						return nil, nil, errorBoom
+					}
+					return &packngo.IPAddressAssignment{}, nil, nil
+				},
+				MockUnassignIPAddress: func(assignmentID string) (*packngo.Response, error) {
+					if assignmentID != "removed" {
+						return nil, errorBoom
+					}
+					return nil, nil
+				},
+			}},
+			args: args{
+				ctx: context.Background(),
+				mg: device(func(i *v1alpha2.Device) {
+					i.Spec.ForProvider.IPAddresses = []v1alpha2.IPAddress{
+						{AddressFamily: 4, Public: true},
+						{AddressFamily: 4, Public: true, Reservations: []string{"elastic"}},
+					}
+				}),
+			},
+			want: want{
+				mg: device(func(i *v1alpha2.Device) {
+					i.Spec.ForProvider.IPAddresses = []v1alpha2.IPAddress{
+						{AddressFamily: 4, Public: true},
+						{AddressFamily: 4, Public: true, Reservations: []string{"elastic"}},
+					}
+				}),
+			},
+		},
+		"RescuedInstance": {
+			client: &external{
+				client: &fake.MockClient{
+					MockGet: func(deviceID string, getOpt *packngo.GetOptions) (*packngo.Device, *packngo.Response, error) {
+						return &packngo.Device{}, nil, nil
+					},
+					MockRescue: func(deviceID string) (*packngo.Response, error) {
+						return nil, nil
+					},
+				},
+				recorder: event.NewNopRecorder(),
+			},
+			args: args{
+				ctx: context.Background(),
+				mg:  device(withRescue(true, false)),
+			},
+			want: want{
+				mg: device(withRescue(true, false), withRescueAction(v1alpha2.RescueActionRescue, time.Now())),
+			},
+		},
+		"RescuePending": {
+			client: &external{
+				client: &fake.MockClient{
+					MockGet: func(deviceID string, getOpt *packngo.GetOptions) (*packngo.Device, *packngo.Response, error) {
+						return &packngo.Device{}, nil, nil
+					},
+					MockRescue: func(deviceID string) (*packngo.Response, error) {
+						return nil, errorBoom
+					},
+				},
+				recorder: event.NewNopRecorder(),
+			},
+			args: args{
+				ctx: context.Background(),
+				mg:  device(withRescue(true, false), withRescueAction(v1alpha2.RescueActionRescue, time.Now())),
+			},
+			want: want{
+				mg: device(withRescue(true, false), withRescueAction(v1alpha2.RescueActionRescue, time.Now())),
+			},
+		},
+		"ExitedRescue": {
+			client: &external{
+				client: &fake.MockClient{
+					MockGet: func(deviceID string, getOpt *packngo.GetOptions) (*packngo.Device, *packngo.Response, error) {
+						return &packngo.Device{OS: &packngo.OS{Slug: devicesclient.RescueOS}}, nil, nil
+					},
+					MockReboot: func(deviceID string) (*packngo.Response, error) {
+						return nil, nil
+					},
+				},
+				recorder: event.NewNopRecorder(),
+			},
+			args: args{
+				ctx: context.Background(),
+				mg:  device(withRescue(false, true)),
+			},
+			want: want{
+				mg: device(withRescue(false, true), withRescueAction(v1alpha2.RescueActionExit, time.Now())),
+				update: managed.ExternalUpdate{ConnectionDetails: managed.ConnectionDetails{
+					devicesclient.ConnectionRescueKey:         []byte("false"),
+					devicesclient.ConnectionRescuePasswordKey: []byte{},
+				}},
+			},
+		},
+		"FailedToReinstallForChangedUserData": {
+			client: &external{
+				client: &fake.MockClient{
+					MockUpdate: func(deviceID string, updateRequest *packngo.DeviceUpdateRequest) (*packngo.Device, *packngo.Response, error) {
+						if updateRequest.UserData == nil || *updateRequest.UserData != "vlan 1000 in sv" {
+							return nil, nil, errorBoom
+						}
+						return &packngo.Device{}, nil, nil
+					},
+					MockGet: func(deviceID string, getOpt *packngo.GetOptions) (*packngo.Device, *packngo.Response, error) {
+						return &packngo.Device{}, nil, nil
+					},
+					MockReinstall: func(deviceID string, r *devicesclient.ReinstallRequest) (*packngo.Response, error) {
+						return nil, errorBoom
+					},
+				},
+				kube: &test.MockClient{
+					MockGet: userdataTemplateGet(int64(1000)),
+				},
+			},
+			args: args{
+				ctx: context.Background(),
+				mg: device(withSpecMetro("sv"), withUserDataTemplate(),
+					withUserDataUpdatePolicy(v1alpha2.UserDataUpdatePolicyReinstall), withUserDataHash("vlan 999 in sv")),
+			},
+			want: want{
+				mg: device(withSpecMetro("sv"), withUserDataTemplate(),
+					withUserDataUpdatePolicy(v1alpha2.UserDataUpdatePolicyReinstall), withUserDataHash("vlan 999 in sv"),
+					withConditions(v1alpha2.UserDataRendered())),
+				err: errors.Wrap(errorBoom, errReinstallDevice),
+			},
+		},
 		"NotCloudMemorystoreInstance": {
 			client: &external{},
 			args: args{
@@ -837,7 +1582,7 @@ func TestUpdate(t *testing.T) {
 				t.Errorf("tc.client.Update(): -want error, +got error:\n%s", diff)
 			}
 
-			if diff := cmp.Diff(tc.want.mg, tc.args.mg, test.EquateConditions(), packettest.EquateQuantities()); diff != "" {
+			if diff := cmp.Diff(tc.want.mg, tc.args.mg, test.EquateConditions(), packettest.EquateQuantities(), packettest.EquateApproxTimes(time.Minute)); diff != "" {
 				t.Errorf("resource.Managed: -want, +got:\n%s", diff)
 			}
 		})
@@ -873,6 +1618,50 @@ func TestDelete(t *testing.T) {
 				mg: device(withConditions(xpv1.Deleting())),
 			},
 		},
+		"LockedInstanceDeletionBlocked": {
+			client: &external{recorder: event.NewNopRecorder()},
+			args: args{
+				ctx: context.Background(),
+				mg:  device(withLocked()),
+			},
+			want: want{
+				mg:  device(withLocked(), withConditions(xpv1.Deleting())),
+				err: errors.New(errDeviceLocked),
+			},
+		},
+		"UnlockedAndForceDeletedInstance": {
+			client: &external{client: &fake.MockClient{
+				MockUpdate: func(deviceID string, updateRequest *packngo.DeviceUpdateRequest) (*packngo.Device, *packngo.Response, error) {
+					if updateRequest.Locked == nil || *updateRequest.Locked {
+						return nil, nil, errorBoom
+					}
+					return &packngo.Device{}, nil, nil
+				},
+				MockDelete: func(deviceID string, force bool) (*packngo.Response, error) {
+					if !force {
+						return nil, errorBoom
+					}
+					return nil, nil
+				}},
+			},
+			args: args{
+				ctx: context.Background(),
+				mg:  device(withLocked(), withState(v1alpha2.StateProvisioning), withDeletionOptions(true, true)),
+			},
+			want: want{
+				mg: device(withLocked(), withState(v1alpha2.StateProvisioning), withDeletionOptions(true, true), withConditions(xpv1.Deleting())),
+			},
+		},
+		"DeprovisioningInstance": {
+			client: &external{},
+			args: args{
+				ctx: context.Background(),
+				mg:  device(withState(v1alpha2.StateDeprovisioning)),
+			},
+			want: want{
+				mg: device(withState(v1alpha2.StateDeprovisioning), withConditions(xpv1.Deleting())),
+			},
+		},
 		"NotDeviceInstance": {
 			client: &external{},
 			args: args{
@@ -916,3 +1705,62 @@ func TestDelete(t *testing.T) {
 		})
 	}
 }
+
+func TestRecordState(t *testing.T) {
+	created := time.Now().Add(-10 * time.Minute).UTC().Format(time.RFC3339)
+
+	cases := map[string]struct {
+		previousState string
+		device        *packngo.Device
+		want          uint64
+	}{
+		"BecameActive": {
+			previousState: v1alpha2.StateProvisioning,
+			device:        &packngo.Device{State: v1alpha2.StateActive, Created: created},
+			want:          1,
+		},
+		"StillActive": {
+			previousState: v1alpha2.StateActive,
+			device:        &packngo.Device{State: v1alpha2.StateActive, Created: created},
+		},
+		"FirstObservation": {
+			device: &packngo.Device{State: v1alpha2.StateActive, Created: created},
+		},
+		"StillProvisioning": {
+			previousState: v1alpha2.StateProvisioning,
+			device:        &packngo.Device{State: v1alpha2.StateProvisioning, Created: created},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d := device()
+			o, err := devicesclient.GenerateObservation(tc.device)
+			if err != nil {
+				t.Fatalf("GenerateObservation(...): %v", err)
+			}
+			d.Status.AtProvider = o
+
+			before := provisioningSamples(t)
+			recordState(d, tc.previousState)
+			if diff := cmp.Diff(tc.want, provisioningSamples(t)-before); diff != "" {
+				t.Errorf("recordState(...): -want observations, +got observations:\n%s", diff)
+			}
+		})
+	}
+}
+
+// provisioningSamples returns the number of provisioning durations observed.
+func provisioningSamples(t *testing.T) uint64 {
+	t.Helper()
+	mfs, err := crmetrics.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather(): %v", err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() == "equinix_metal_device_provisioning_duration_seconds" {
+			return mf.GetMetric()[0].GetHistogram().GetSampleCount()
+		}
+	}
+	return 0
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package device
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+
+	v1alpha2 "github.com/packethost/crossplane-provider-equinix-metal/apis/server/v1alpha2"
+)
+
+// userDataRefsIndex indexes Devices by the ConfigMaps and Secrets their
+// userdata is resolved from.
+const userDataRefsIndex = "spec.forProvider.userdataRefs"
+
+// indexUserDataRefs returns the keys of the ConfigMaps and Secrets the
+// userdata of the supplied Device is resolved from.
+func indexUserDataRefs(o client.Object) []string {
+	d, ok := o.(*v1alpha2.Device)
+	if !ok {
+		return nil
+	}
+
+	fp := d.Spec.ForProvider
+	var keys []string
+	if r := fp.UserDataRef; r != nil {
+		keys = append(keys, refKey(r.Kind, r.Namespace, r.Name))
+	}
+	for _, src := range fp.UserDataSources {
+		if r := src.Ref; r != nil {
+			keys = append(keys, refKey(r.Kind, r.Namespace, r.Name))
+		}
+	}
+	if t := fp.UserDataTemplate; t != nil {
+		for _, s := range t.Secrets {
+			keys = append(keys, refKey("Secret", s.SecretRef.Namespace, s.SecretRef.Name))
+		}
+	}
+	return keys
+}
+
+func refKey(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
+
+// enqueueReferencingDevices returns a function that enqueues the Devices whose
+// userdata is resolved from a ConfigMap or Secret of the supplied kind when it
+// changes.
+func enqueueReferencingDevices(c client.Reader, l logging.Logger, kind string) handler.MapFunc {
+	return func(o client.Object) []reconcile.Request {
+		devices := &v1alpha2.DeviceList{}
+		if err := c.List(context.Background(), devices, client.MatchingFields{userDataRefsIndex: refKey(kind, o.GetNamespace(), o.GetName())}); err != nil {
+			l.Info("cannot list Devices referencing changed userdata", "kind", kind, "namespace", o.GetNamespace(), "name", o.GetName(), "error", err)
+			return nil
+		}
+
+		reqs := make([]reconcile.Request, len(devices.Items))
+		for i := range devices.Items {
+			reqs[i] = reconcile.Request{NamespacedName: types.NamespacedName{Name: devices.Items[i].GetName()}}
+		}
+		return reqs
+	}
+}
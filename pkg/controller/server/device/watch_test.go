@@ -0,0 +1,73 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package device
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	"github.com/packethost/crossplane-provider-equinix-metal/apis/server/v1alpha2"
+)
+
+func TestIndexUserDataRefs(t *testing.T) {
+	d := device(withUserDataTemplate(), func(i *v1alpha2.Device) {
+		i.Spec.ForProvider.UserDataTemplate.Secrets = []v1alpha2.TemplateSecretReference{
+			{Name: "creds", SecretRef: v1alpha2.NamespacedName{Namespace: namespace, Name: "creds"}},
+		}
+	})
+
+	want := []string{"ConfigMap/cool-namespace/userdata", "Secret/cool-namespace/creds"}
+	if diff := cmp.Diff(want, indexUserDataRefs(d)); diff != "" {
+		t.Errorf("indexUserDataRefs(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestEnqueueReferencingDevices(t *testing.T) {
+	c := &test.MockClient{
+		MockList: func(_ context.Context, obj client.ObjectList, opts ...client.ListOption) error {
+			lo := &client.ListOptions{}
+			lo.ApplyOptions(opts)
+			if !lo.FieldSelector.Matches(fields.Set{userDataRefsIndex: "ConfigMap/cool-namespace/userdata"}) {
+				return nil
+			}
+			obj.(*v1alpha2.DeviceList).Items = []v1alpha2.Device{*device()}
+			return nil
+		},
+	}
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "userdata"}}
+
+	got := enqueueReferencingDevices(c, logging.NewNopLogger(), "ConfigMap")(cm)
+	want := []reconcile.Request{{NamespacedName: types.NamespacedName{Name: deviceName}}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("enqueueReferencingDevices(...): -want, +got:\n%s", diff)
+	}
+
+	if got := enqueueReferencingDevices(c, logging.NewNopLogger(), "Secret")(cm); len(got) != 0 {
+		t.Errorf("enqueueReferencingDevices(...): want no requests, got %v", got)
+	}
+}
@@ -20,15 +20,18 @@ import (
 	"context"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/packethost/packngo"
 	"github.com/pkg/errors"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 
 	packetv1beta1 "github.com/packethost/crossplane-provider-equinix-metal/apis/v1beta1"
 	"github.com/packethost/crossplane-provider-equinix-metal/apis/vlan/v1alpha1"
 	"github.com/packethost/crossplane-provider-equinix-metal/pkg/clients"
 	packetclient "github.com/packethost/crossplane-provider-equinix-metal/pkg/clients"
 	vlanclient "github.com/packethost/crossplane-provider-equinix-metal/pkg/clients/vlan"
+	"github.com/packethost/crossplane-provider-equinix-metal/pkg/tracing"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 	"github.com/crossplane/crossplane-runtime/pkg/event"
@@ -51,23 +54,39 @@ const (
 	errDeleteVirtualNetwork    = "cannot delete VirtualNetwork"
 )
 
+// errorReasons are the error messages by which the reconcile errors of the
+// controller are counted.
+var errorReasons = []string{
+	errManagedUpdateFailed,
+	errGenObservation,
+	errNotVirtualNetwork,
+	errGetVirtualNetwork,
+	errCreateVirtualNetwork,
+	errDeleteVirtualNetwork,
+}
+
 // SetupVirtualNetwork adds a controller that reconciles VirtualNetworks
-func SetupVirtualNetwork(mgr ctrl.Manager, l logging.Logger) error {
+func SetupVirtualNetwork(mgr ctrl.Manager, l logging.Logger, cc *clients.ClientCache) error {
 	name := managed.ControllerName(v1alpha1.VirtualNetworkGroupKind)
 
+	rl := clients.NewRateLimiter(nil)
 	r := managed.NewReconciler(mgr,
 		resource.ManagedKind(v1alpha1.VirtualNetworkGroupVersionKind),
 		managed.WithExternalConnecter(&connecter{
-			kube:  mgr.GetClient(),
-			usage: resource.NewProviderConfigUsageTracker(mgr.GetClient(), &packetv1beta1.ProviderConfigUsage{}),
+			kube:    mgr.GetClient(),
+			usage:   resource.NewProviderConfigUsageTracker(mgr.GetClient(), &packetv1beta1.ProviderConfigUsage{}),
+			limiter: rl,
+			cache:   cc,
 		}),
 		managed.WithConnectionPublishers(),
+		managed.WithReferenceResolver(managed.NewAPISimpleReferenceResolver(mgr.GetClient())),
 		managed.WithLogger(l.WithValues("controller", name)),
 		managed.WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
 	)
 
 	return ctrl.NewControllerManagedBy(mgr).
 		Named(name).
+		WithOptions(controller.Options{RateLimiter: rl}).
 		For(&v1alpha1.VirtualNetwork{}).
 		Complete(r)
 }
@@ -75,11 +94,14 @@ func SetupVirtualNetwork(mgr ctrl.Manager, l logging.Logger) error {
 type connecter struct {
 	kube        client.Client
 	usage       resource.Tracker
-	newClientFn func(ctx context.Context, config *clients.Credentials) (vlanclient.ClientWithDefaults, error)
+	limiter     *clients.RateLimiter
+	cache       *clients.ClientCache
+	newClientFn func(ctx context.Context, client *clients.Client) (vlanclient.ClientWithDefaults, error)
 }
 
 func (c *connecter) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
-	if _, ok := mg.(*v1alpha1.VirtualNetwork); !ok {
+	v, ok := mg.(*v1alpha1.VirtualNetwork)
+	if !ok {
 		return nil, errors.New(errNotVirtualNetwork)
 	}
 
@@ -87,22 +109,31 @@ func (c *connecter) Connect(ctx context.Context, mg resource.Managed) (managed.E
 		return nil, errors.Wrap(err, errTrackPCUsage)
 	}
 
-	newClientFn := vlanclient.NewClient
+	newClientFn := vlanclient.NewClientFromAPI
 	if c.newClientFn != nil {
 		newClientFn = c.newClientFn
 	}
-	cfg, err := clients.GetAuthInfo(ctx, c.kube, mg)
+	cl, err := c.cache.GetClient(ctx, c.kube, mg)
 	if err != nil {
 		return nil, errors.Wrap(err, errGetProviderConfigSecret)
 	}
-	client, err := newClientFn(ctx, cfg)
-
-	return &external{kube: c.kube, client: client}, errors.Wrap(err, errNewClient)
+	scope := tracing.NewScope()
+	client, err := newClientFn(ctx, cl.WithScope(scope))
+
+	e := &external{
+		kube:     c.kube,
+		client:   client,
+		projects: cl.Client.Projects,
+		snapshot: c.cache.Snapshot(cl, cl.GetProjectID(v.Spec.ForProvider.ProjectID)),
+	}
+	return tracing.NewExternalClient(clients.NewClassifyingExternalClient(e, c.limiter, errorReasons...), scope), errors.Wrap(err, errNewClient)
 }
 
 type external struct {
-	kube   client.Client
-	client vlanclient.ClientWithDefaults
+	kube     client.Client
+	client   vlanclient.ClientWithDefaults
+	projects clients.ProjectClient
+	snapshot *clients.ProjectSnapshot
 }
 
 func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
@@ -112,7 +143,7 @@ func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 	}
 
 	// Observe virtual network
-	device, _, err := e.client.Get(meta.GetExternalName(v), nil)
+	device, err := e.getVirtualNetwork(meta.GetExternalName(v))
 	if packetclient.IsNotFound(err) {
 		return managed.ExternalObservation{ResourceExists: false}, nil
 	}
@@ -143,6 +174,16 @@ func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 	return o, nil
 }
 
+// getVirtualNetwork returns the VirtualNetwork from the project snapshot, or
+// from the API if it is missing from the snapshot.
+func (e *external) getVirtualNetwork(id string) (*packngo.VirtualNetwork, error) {
+	if vlan, ok := e.snapshot.VirtualNetwork(id); ok {
+		return vlan, nil
+	}
+	vlan, _, err := e.client.Get(id, nil)
+	return vlan, err
+}
+
 func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
 	v, ok := mg.(*v1alpha1.VirtualNetwork)
 	if !ok {
@@ -151,17 +192,34 @@ func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.Ext
 
 	v.Status.SetConditions(xpv1.Creating())
 
+	if p := v.Spec.ForProvider.ProjectID; p != "" {
+		if err := packetclient.ValidateProject(e.projects, p); err != nil {
+			return managed.ExternalCreation{}, errors.Wrap(err, errCreateVirtualNetwork)
+		}
+	}
+
+	// VirtualNetworks that specify neither facility nor metro are created in
+	// the default location of the ProviderConfig, which is recorded in status.
 	create := vlanclient.CreateFromVirtualNetwork(v, e.client.GetProjectID(packetclient.CredentialProjectID))
+	create.Facility, create.Metro = packetclient.DefaultLocation(e.client, create.Facility, create.Metro)
 	vlan, _, err := e.client.Create(create)
 	if err != nil {
 		return managed.ExternalCreation{}, errors.Wrap(err, errCreateVirtualNetwork)
 	}
 
 	v.Status.AtProvider.ID = vlan.ID
+	v.Status.AtProvider.FacilityCode = create.Facility
+	v.Status.AtProvider.Metro = create.Metro
 	meta.SetExternalName(v, vlan.ID)
+
+	// Updating the VirtualNetwork replaces its status with the stored status.
+	// It is restored so that the managed reconciler persists it once Create
+	// returns.
+	status := v.Status.DeepCopy()
 	if err := e.kube.Update(ctx, v); err != nil {
 		return managed.ExternalCreation{}, errors.Wrap(err, errManagedUpdateFailed)
 	}
+	v.Status = *status
 
 	return managed.ExternalCreation{}, nil
 }
@@ -178,6 +236,7 @@ func (e *external) Delete(ctx context.Context, mg resource.Managed) error {
 	}
 	v.SetConditions(xpv1.Deleting())
 
+	e.snapshot.Forget(meta.GetExternalName(v))
 	_, err := e.client.Delete(meta.GetExternalName(v))
 	return errors.Wrap(resource.Ignore(packetclient.IsNotFound, err), errDeleteVirtualNetwork)
 }
@@ -0,0 +1,186 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics contains the Prometheus metrics of the Equinix Metal
+// provider. They are registered with the controller-runtime metrics registry
+// and served by the controller manager's metrics endpoint.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const namespace = "equinix_metal"
+
+var (
+	apiRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_requests_total",
+		Help:      "Number of Equinix Metal API requests by method, endpoint and HTTP status code.",
+	}, []string{"method", "endpoint", "code"})
+
+	apiRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "api_request_duration_seconds",
+		Help:      "Latency of Equinix Metal API requests by method and endpoint.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "endpoint"})
+
+	apiThrottled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_throttled_total",
+		Help:      "Number of Equinix Metal API requests rejected by rate limiting, by method and endpoint.",
+	}, []string{"method", "endpoint"})
+
+	provisioningDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "device_provisioning_duration_seconds",
+		Help:      "Time taken by Devices to become active after they were created.",
+		Buckets:   prometheus.ExponentialBuckets(60, 1.5, 12),
+	})
+
+	reconcileErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reconcile_errors_total",
+		Help:      "Number of errors returned by managed resource reconciles, by kind, reason and class.",
+	}, []string{"kind", "reason", "class"})
+
+	devices = &deviceStates{states: map[string]string{}}
+)
+
+func init() {
+	metrics.Registry.MustRegister(apiRequests, apiRequestDuration, apiThrottled, provisioningDuration, reconcileErrors, devices)
+}
+
+// ObserveAPIRequest records an Equinix Metal API request that completed with
+// the supplied status code, or with a zero code if no response was received.
+func ObserveAPIRequest(req *http.Request, code int, d time.Duration) {
+	ep := Endpoint(req.URL.Path)
+	c := strconv.Itoa(code)
+	if code == 0 {
+		c = "error"
+	}
+	apiRequests.WithLabelValues(req.Method, ep, c).Inc()
+	apiRequestDuration.WithLabelValues(req.Method, ep).Observe(d.Seconds())
+	if code == http.StatusTooManyRequests {
+		apiThrottled.WithLabelValues(req.Method, ep).Inc()
+	}
+}
+
+// Endpoint returns the supplied Equinix Metal API path with its API version
+// prefix removed and resource identifiers replaced by {id}, so that requests
+// for different resources of the same kind share an endpoint label.
+func Endpoint(path string) string {
+	path = strings.TrimPrefix(path, "/metal")
+	path = strings.TrimPrefix(path, "/v1")
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i, p := range parts {
+		if isIdentifier(p) {
+			parts[i] = "{id}"
+		}
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+// isIdentifier returns true for UUIDs and numeric identifiers.
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	if len(s) == 36 && strings.Count(s, "-") == 4 {
+		return true
+	}
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+// ObserveProvisioned records the time taken by a Device to become active.
+func ObserveProvisioned(d time.Duration) {
+	provisioningDuration.Observe(d.Seconds())
+}
+
+// ReasonOther is the reason by which reconcile errors that are not described
+// by one of the error messages of their controller are counted.
+const ReasonOther = "other"
+
+// RecordReconcileError records an error returned while reconciling a managed
+// resource of the supplied kind. The reason is the error message constant of
+// the controller that describes the error, and the class how it is retried,
+// so that the number of series is bounded.
+func RecordReconcileError(kind, reason, class string) {
+	reconcileErrors.WithLabelValues(kind, reason, class).Inc()
+}
+
+// SetDeviceState records the observed state of the named Device.
+func SetDeviceState(name, state string) {
+	devices.set(name, state)
+}
+
+// ForgetDevice stops counting the named Device.
+func ForgetDevice(name string) {
+	devices.forget(name)
+}
+
+// deviceStates is a Prometheus collector that counts Devices by their most
+// recently observed state.
+type deviceStates struct {
+	mu     sync.Mutex
+	states map[string]string
+}
+
+var devicesDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "devices"),
+	"Number of Devices by their most recently observed state.",
+	[]string{"state"}, nil,
+)
+
+func (s *deviceStates) set(name, state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[name] = state
+}
+
+func (s *deviceStates) forget(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.states, name)
+}
+
+// Describe implements prometheus.Collector.
+func (s *deviceStates) Describe(ch chan<- *prometheus.Desc) {
+	ch <- devicesDesc
+}
+
+// Collect implements prometheus.Collector.
+func (s *deviceStates) Collect(ch chan<- prometheus.Metric) {
+	s.mu.Lock()
+	counts := map[string]int{}
+	for _, state := range s.states {
+		counts[state]++
+	}
+	s.mu.Unlock()
+
+	for state, n := range counts {
+		ch <- prometheus.MustNewConstMetric(devicesDesc, prometheus.GaugeValue, float64(n), state)
+	}
+}
@@ -17,8 +17,11 @@ limitations under the License.
 package test
 
 import (
+	"time"
+
 	"github.com/google/go-cmp/cmp"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // EquateQuantities returns true if the supplied quantities produce identical
@@ -28,3 +31,12 @@ func EquateQuantities() cmp.Option {
 		return a.Value() == b.Value()
 	})
 }
+
+// EquateApproxTimes returns true if the supplied times are within the supplied
+// margin of each other.
+func EquateApproxTimes(margin time.Duration) cmp.Option {
+	return cmp.Comparer(func(a, b metav1.Time) bool {
+		d := a.Sub(b.Time)
+		return d <= margin && d >= -margin
+	})
+}
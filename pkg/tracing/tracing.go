@@ -0,0 +1,272 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracing contains the optional OpenTelemetry tracing of the Equinix
+// Metal provider. When enabled, every Observe, Create, Update and Delete of a
+// managed resource is traced, with a child span for each Equinix Metal API
+// request it makes.
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/packethost/crossplane-provider-equinix-metal/pkg/metrics"
+	"github.com/packethost/crossplane-provider-equinix-metal/pkg/version"
+)
+
+const (
+	instrumentationName = "github.com/packethost/crossplane-provider-equinix-metal"
+	serviceName         = "provider-equinix-metal"
+
+	// headerRequestID is the response header in which the Equinix Metal API
+	// returns the identifier of each request.
+	headerRequestID = "X-Request-Id"
+
+	errNewExporter = "cannot create OTLP trace exporter"
+	errNewResource = "cannot create trace resource"
+)
+
+var (
+	mu      sync.RWMutex
+	enabled bool
+)
+
+// Options configure the export of traces.
+type Options struct {
+	// Endpoint is the host:port of the OTLP gRPC collector traces are
+	// exported to. Tracing is disabled when it is empty.
+	Endpoint string
+
+	// Insecure disables TLS when connecting to the collector.
+	Insecure bool
+
+	// SampleRatio is the fraction of reconciles that are traced, unless the
+	// trace is sampled by a parent span.
+	SampleRatio float64
+}
+
+// Setup configures the global tracer provider to export traces to the OTLP
+// collector described by the supplied options. The returned function flushes
+// and stops the export of traces. Setup does nothing if no endpoint is
+// configured.
+func Setup(ctx context.Context, o Options) (func(context.Context) error, error) {
+	if o.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	eo := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(o.Endpoint)}
+	if o.Insecure {
+		eo = append(eo, otlptracegrpc.WithInsecure())
+	}
+	exp, err := otlptracegrpc.New(ctx, eo...)
+	if err != nil {
+		return nil, errors.Wrap(err, errNewExporter)
+	}
+
+	res, err := sdkresource.Merge(sdkresource.Default(), sdkresource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceNameKey.String(serviceName),
+		semconv.ServiceVersionKey.String(version.Version),
+	))
+	if err != nil {
+		return nil, errors.Wrap(err, errNewResource)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(o.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	mu.Lock()
+	enabled = true
+	mu.Unlock()
+
+	return tp.Shutdown, nil
+}
+
+// Enabled returns true if traces are exported.
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return enabled
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// A Scope carries the span of the managed resource operation in progress to
+// the API requests it makes. The Equinix Metal API client does not accept a
+// context, so its requests are parented by the Scope of the client instead.
+// A Scope is used by one reconcile at a time.
+type Scope struct {
+	mu  sync.Mutex
+	ctx context.Context
+}
+
+// NewScope returns a new Scope, or nil if tracing is disabled. A nil Scope
+// traces nothing.
+func NewScope() *Scope {
+	if !Enabled() {
+		return nil
+	}
+	return &Scope{ctx: context.Background()}
+}
+
+func (s *Scope) enter(ctx context.Context) func() {
+	s.mu.Lock()
+	prev := s.ctx
+	s.ctx = ctx
+	s.mu.Unlock()
+
+	return func() {
+		s.mu.Lock()
+		s.ctx = prev
+		s.mu.Unlock()
+	}
+}
+
+func (s *Scope) context() context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ctx
+}
+
+// Transport returns an http.RoundTripper that traces each request sent through
+// the supplied base as a child of the current span of the Scope. It returns
+// base if the Scope is nil.
+func (s *Scope) Transport(base http.RoundTripper) http.RoundTripper {
+	if s == nil {
+		return base
+	}
+	return &transport{scope: s, base: base}
+}
+
+type transport struct {
+	scope *Scope
+	base  http.RoundTripper
+}
+
+// RoundTrip traces the supplied request. The span covers every attempt made
+// by the base transport, which may record its retries as span events.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	_, span := tracer().Start(t.scope.context(), req.Method+" "+metrics.Endpoint(req.URL.Path),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.HTTPClientAttributesFromHTTPRequest(req)...),
+	)
+	defer span.End()
+
+	resp, err := t.base.RoundTrip(req.WithContext(trace.ContextWithSpan(req.Context(), span)))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return resp, err
+	}
+
+	span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(resp.StatusCode)...)
+	span.SetStatus(semconv.SpanStatusFromHTTPStatusCode(resp.StatusCode))
+	if id := resp.Header.Get(headerRequestID); id != "" {
+		span.SetAttributes(attribute.String("equinix_metal.request_id", id))
+	}
+	return resp, nil
+}
+
+// tracingExternal is a managed.ExternalClient that traces each operation of
+// the ExternalClient it wraps.
+type tracingExternal struct {
+	managed.ExternalClient
+	scope *Scope
+}
+
+// NewExternalClient wraps the supplied ExternalClient such that each of its
+// operations is traced, and parents the API requests made through the supplied
+// Scope. It returns e if the Scope is nil.
+func NewExternalClient(e managed.ExternalClient, s *Scope) managed.ExternalClient {
+	if s == nil {
+		return e
+	}
+	return &tracingExternal{ExternalClient: e, scope: s}
+}
+
+func (t *tracingExternal) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	ctx, end := t.start(ctx, "Observe", mg)
+	o, err := t.ExternalClient.Observe(ctx, mg)
+	end(err, attribute.Bool("crossplane.resource_exists", o.ResourceExists), attribute.Bool("crossplane.resource_up_to_date", o.ResourceUpToDate))
+	return o, err
+}
+
+func (t *tracingExternal) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	ctx, end := t.start(ctx, "Create", mg)
+	c, err := t.ExternalClient.Create(ctx, mg)
+	end(err, attribute.String("crossplane.external_name", meta.GetExternalName(mg)))
+	return c, err
+}
+
+func (t *tracingExternal) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	ctx, end := t.start(ctx, "Update", mg)
+	u, err := t.ExternalClient.Update(ctx, mg)
+	end(err)
+	return u, err
+}
+
+func (t *tracingExternal) Delete(ctx context.Context, mg resource.Managed) error {
+	ctx, end := t.start(ctx, "Delete", mg)
+	err := t.ExternalClient.Delete(ctx, mg)
+	end(err)
+	return err
+}
+
+// start a span for the supplied operation and make it the current span of the
+// Scope. The returned function ends the span with the supplied error and
+// attributes.
+func (t *tracingExternal) start(ctx context.Context, op string, mg resource.Managed) (context.Context, func(error, ...attribute.KeyValue)) {
+	kind := reflect.TypeOf(mg).Elem().Name()
+	ctx, span := tracer().Start(ctx, kind+"."+op, trace.WithAttributes(
+		attribute.String("crossplane.kind", kind),
+		attribute.String("crossplane.name", mg.GetName()),
+		attribute.String("crossplane.external_name", meta.GetExternalName(mg)),
+	))
+	exit := t.scope.enter(ctx)
+
+	return ctx, func(err error, kv ...attribute.KeyValue) {
+		exit()
+		span.SetAttributes(kv...)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/packethost/crossplane-provider-equinix-metal/apis/ports/v1alpha1"
+)
+
+type assignmentValidator struct{}
+
+func (assignmentValidator) new() client.Object { return &v1alpha1.Assignment{} }
+
+func (assignmentValidator) validateCreate(obj client.Object) field.ErrorList {
+	fp := obj.(*v1alpha1.Assignment).Spec.ForProvider
+
+	var errs field.ErrorList
+	if fp.Name == "" {
+		errs = append(errs, field.Required(forProvider.Child("name"), "the name of a port of the device"))
+	}
+	if fp.DeviceID == "" && fp.DeviceIDRef == nil && fp.DeviceIDSelector == nil {
+		errs = append(errs, field.Required(forProvider.Child("deviceId"), "one of deviceId, deviceIdRef or deviceIdSelector"))
+	}
+	if fp.VirtualNetworkID == "" && fp.VirtualNetworkIDRef == nil && fp.VirtualNetworkIDSelector == nil {
+		errs = append(errs, field.Required(forProvider.Child("virtualNetworkId"), "one of virtualNetworkId, virtualNetworkIdRef or virtualNetworkIdSelector"))
+	}
+	return errs
+}
+
+// validateUpdate allows the device and virtual network to be resolved from
+// their references, but not to change once known.
+func (assignmentValidator) validateUpdate(oldObj, obj client.Object) field.ErrorList {
+	o, n := oldObj.(*v1alpha1.Assignment).Spec.ForProvider, obj.(*v1alpha1.Assignment).Spec.ForProvider
+
+	errs := immutable(forProvider.Child("name"), o.Name, n.Name)
+	errs = append(errs, immutableOnceSet(forProvider.Child("deviceId"), o.DeviceID, n.DeviceID, o.DeviceID == "")...)
+	errs = append(errs, immutableOnceSet(forProvider.Child("virtualNetworkId"), o.VirtualNetworkID, n.VirtualNetworkID, o.VirtualNetworkID == "")...)
+	return errs
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"regexp"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/packethost/crossplane-provider-equinix-metal/apis/server/v1alpha2"
+	devicesclient "github.com/packethost/crossplane-provider-equinix-metal/pkg/clients/device"
+)
+
+var forProvider = field.NewPath("spec", "forProvider")
+
+// templateName matches names that may be used as keys in a template, such as
+// .Resources.vlan.
+var templateName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// Kinds of resource a Device userdataRef may refer to.
+var userDataRefKinds = []string{"ConfigMap", "Secret"}
+
+type deviceValidator struct{}
+
+func (deviceValidator) new() client.Object { return &v1alpha2.Device{} }
+
+func (deviceValidator) validateCreate(obj client.Object) field.ErrorList {
+	fp := obj.(*v1alpha2.Device).Spec.ForProvider
+	return append(validateLocation(forProvider, fp.Facility, fp.Metro), validateParameters(fp)...)
+}
+
+// validateParameters returns the errors in the supplied parameters that are
+// checked until the Device is created, other than in its location.
+func validateParameters(fp v1alpha2.DeviceParameters) field.ErrorList {
+	var errs field.ErrorList
+	if fp.UserData != nil && fp.UserDataRef != nil {
+		errs = append(errs, field.Forbidden(forProvider.Child("userdataRef"), "may not be specified together with userdata"))
+	}
+	errs = append(errs, validateUserDataRef(forProvider.Child("userdataRef"), fp.UserDataRef)...)
+	errs = append(errs, validateUserDataSources(forProvider.Child("userdataSources"), fp)...)
+	errs = append(errs, validateUserDataTemplate(forProvider.Child("userdataTemplate"), fp.UserDataTemplate, fp.UserDataRef != nil || len(fp.UserDataSources) > 0)...)
+	errs = append(errs, validateTermination(forProvider, fp)...)
+	errs = append(errs, devicesclient.ValidateStorage(forProvider.Child("storage"), fp.Storage)...)
+	for i, ip := range fp.IPAddresses {
+		if ip.AddressFamily != 4 && ip.AddressFamily != 6 {
+			errs = append(errs, field.NotSupported(forProvider.Child("ipAddresses").Index(i).Child("address_family"), ip.AddressFamily, []string{"4", "6"}))
+		}
+	}
+	return errs
+}
+
+func (deviceValidator) validateUpdate(oldObj, obj client.Object) field.ErrorList {
+	old, d := oldObj.(*v1alpha2.Device), obj.(*v1alpha2.Device)
+	o, n := old.Spec.ForProvider, d.Spec.ForProvider
+
+	// Nothing has been created from the spec yet, so it may change freely.
+	// The metro late-initialized alongside the facility is allowed.
+	if !created(old) {
+		errs := validateParameters(n)
+		if metroLateInitialized(o.Facility, o.Metro, n.Facility, n.Metro) {
+			return errs
+		}
+		return append(validateLocation(forProvider, n.Facility, n.Metro), errs...)
+	}
+
+	errs := validateUserDataRef(forProvider.Child("userdataRef"), n.UserDataRef)
+	errs = append(errs, validateUserDataSources(forProvider.Child("userdataSources"), n)...)
+	errs = append(errs, validateUserDataTemplate(forProvider.Child("userdataTemplate"), n.UserDataTemplate, n.UserDataRef != nil || len(n.UserDataSources) > 0)...)
+	errs = append(errs, validateTermination(forProvider, n)...)
+	errs = append(errs, immutableOnceSet(forProvider.Child("projectID"), o.ProjectID, n.ProjectID, o.ProjectID == "")...)
+	errs = append(errs, immutable(forProvider.Child("plan"), o.Plan, n.Plan)...)
+	errs = append(errs, immutable(forProvider.Child("operatingSystem"), o.OS, n.OS)...)
+	errs = append(errs, immutable(forProvider.Child("hardwareReservationID"), o.HardwareReservationID, n.HardwareReservationID)...)
+	errs = append(errs, immutableOnceSet(forProvider.Child("publicIPv4SubnetSize"), o.PublicIPv4SubnetSize, n.PublicIPv4SubnetSize, o.PublicIPv4SubnetSize == nil)...)
+	errs = append(errs, immutable(forProvider.Child("userSSHKeys"), o.UserSSHKeys, n.UserSSHKeys)...)
+	errs = append(errs, immutable(forProvider.Child("projectSSHKeys"), o.ProjectSSHKeys, n.ProjectSSHKeys)...)
+	errs = append(errs, immutable(forProvider.Child("features"), o.Features, n.Features)...)
+	errs = append(errs, immutable(forProvider.Child("storage"), o.Storage, n.Storage)...)
+	errs = append(errs, validateLocationUpdate(forProvider, o.Facility, o.Metro, n.Facility, n.Metro,
+		d.Status.AtProvider.Facility, d.Status.AtProvider.Metro)...)
+	return errs
+}
+
+// validateUserDataRef returns an error if the supplied reference cannot
+// resolve to a ConfigMap or Secret.
+func validateUserDataRef(p *field.Path, ref *v1alpha2.DataKeySelector) field.ErrorList {
+	if ref == nil {
+		return nil
+	}
+
+	var errs field.ErrorList
+	if ref.Kind != userDataRefKinds[0] && ref.Kind != userDataRefKinds[1] {
+		errs = append(errs, field.NotSupported(p.Child("kind"), ref.Kind, userDataRefKinds))
+	}
+	if ref.Name == "" {
+		errs = append(errs, field.Required(p.Child("name"), ""))
+	}
+	if ref.Namespace == "" {
+		errs = append(errs, field.Required(p.Child("namespace"), ""))
+	}
+	return errs
+}
+
+// validateTermination returns an error if the supplied parameters specify
+// both a termination time and a TTL, or a TTL that is not positive.
+func validateTermination(p *field.Path, fp v1alpha2.DeviceParameters) field.ErrorList {
+	var errs field.ErrorList
+	if fp.TerminationTime != nil && fp.TTL != nil {
+		errs = append(errs, field.Forbidden(p.Child("ttl"), "may not be specified together with terminationTime"))
+	}
+	if fp.TTL != nil && fp.TTL.Duration <= 0 {
+		errs = append(errs, field.Invalid(p.Child("ttl"), fp.TTL.Duration.String(), "must be positive"))
+	}
+	return errs
+}
+
+// validateUserDataSources returns an error if the supplied parameters specify
+// userdata sources together with other userdata, or if a source does not
+// specify exactly one of its inline content or a reference.
+func validateUserDataSources(p *field.Path, fp v1alpha2.DeviceParameters) field.ErrorList {
+	if len(fp.UserDataSources) == 0 {
+		return nil
+	}
+
+	var errs field.ErrorList
+	if fp.UserData != nil || fp.UserDataRef != nil {
+		errs = append(errs, field.Forbidden(p, "may not be specified together with userdata or userdataRef"))
+	}
+	for i, src := range fp.UserDataSources {
+		sp := p.Index(i)
+		switch {
+		case src.Inline != nil && src.Ref != nil:
+			errs = append(errs, field.Forbidden(sp.Child("ref"), "may not be specified together with inline"))
+		case src.Inline == nil && src.Ref == nil:
+			errs = append(errs, field.Required(sp, "one of inline or ref"))
+		}
+		errs = append(errs, validateUserDataRef(sp.Child("ref"), src.Ref)...)
+	}
+	return errs
+}
+
+// validateUserDataTemplate returns an error if the supplied template does not
+// render referenced userdata, or if its references are incomplete or are not
+// uniquely named.
+func validateUserDataTemplate(p *field.Path, t *v1alpha2.UserDataTemplate, hasRef bool) field.ErrorList {
+	if t == nil {
+		return nil
+	}
+
+	var errs field.ErrorList
+	if !hasRef {
+		errs = append(errs, field.Forbidden(p, "may only be specified together with userdataRef or userdataSources"))
+	}
+
+	resources := sets.NewString()
+	for i, r := range t.Resources {
+		rp := p.Child("resources").Index(i)
+		errs = append(errs, validateTemplateName(rp.Child("name"), r.Name, resources)...)
+		if r.ResourceRef.APIVersion == "" {
+			errs = append(errs, field.Required(rp.Child("resourceRef", "apiVersion"), ""))
+		}
+		if r.ResourceRef.Kind == "" {
+			errs = append(errs, field.Required(rp.Child("resourceRef", "kind"), ""))
+		}
+		if r.ResourceRef.Name == "" {
+			errs = append(errs, field.Required(rp.Child("resourceRef", "name"), ""))
+		}
+	}
+
+	secrets := sets.NewString()
+	for i, r := range t.Secrets {
+		rp := p.Child("secrets").Index(i)
+		errs = append(errs, validateTemplateName(rp.Child("name"), r.Name, secrets)...)
+		if r.SecretRef.Name == "" {
+			errs = append(errs, field.Required(rp.Child("secretRef", "name"), ""))
+		}
+		if r.SecretRef.Namespace == "" {
+			errs = append(errs, field.Required(rp.Child("secretRef", "namespace"), ""))
+		}
+	}
+	return errs
+}
+
+// validateTemplateName returns an error if the supplied name cannot be used
+// as a key in a template, or is already in the supplied set.
+func validateTemplateName(p *field.Path, name string, seen sets.String) field.ErrorList {
+	switch {
+	case name == "":
+		return field.ErrorList{field.Required(p, "")}
+	case !templateName.MatchString(name):
+		return field.ErrorList{field.Invalid(p, name, "must start with a letter and contain only letters, digits and underscores")}
+	case seen.Has(name):
+		return field.ErrorList{field.Duplicate(p, name)}
+	}
+	seen.Insert(name)
+	return nil
+}
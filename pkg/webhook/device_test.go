@@ -0,0 +1,295 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/packethost/packngo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+
+	"github.com/packethost/crossplane-provider-equinix-metal/apis/server/v1alpha2"
+	devicesclient "github.com/packethost/crossplane-provider-equinix-metal/pkg/clients/device"
+)
+
+type deviceModifier func(*v1alpha2.Device)
+
+func withFacility(f string) deviceModifier {
+	return func(d *v1alpha2.Device) { d.Spec.ForProvider.Facility = f }
+}
+
+func withMetro(m string) deviceModifier {
+	return func(d *v1alpha2.Device) { d.Spec.ForProvider.Metro = m }
+}
+
+func withPlan(p string) deviceModifier {
+	return func(d *v1alpha2.Device) { d.Spec.ForProvider.Plan = p }
+}
+
+func withUserDataRef(kind string) deviceModifier {
+	return func(d *v1alpha2.Device) {
+		d.Spec.ForProvider.UserDataRef = &v1alpha2.DataKeySelector{
+			NamespacedName: v1alpha2.NamespacedName{Namespace: "default", Name: "userdata"},
+			Kind:           kind,
+		}
+	}
+}
+
+func withUserDataTemplate(t *v1alpha2.UserDataTemplate) deviceModifier {
+	return func(d *v1alpha2.Device) { d.Spec.ForProvider.UserDataTemplate = t }
+}
+
+func withStorage(st *v1alpha2.Storage) deviceModifier {
+	return func(d *v1alpha2.Device) { d.Spec.ForProvider.Storage = st }
+}
+
+func withExternalName(n string) deviceModifier {
+	return func(d *v1alpha2.Device) { meta.SetExternalName(d, n) }
+}
+
+func withObserved(facility, metro string) deviceModifier {
+	return func(d *v1alpha2.Device) {
+		meta.SetExternalName(d, "id")
+		d.Status.AtProvider.Facility = facility
+		d.Status.AtProvider.Metro = metro
+	}
+}
+
+func device(m ...deviceModifier) *v1alpha2.Device {
+	d := &v1alpha2.Device{ObjectMeta: metav1.ObjectMeta{Name: "device"}}
+	d.Spec.ForProvider.Plan = "c3.small.x86"
+	d.Spec.ForProvider.OS = "ubuntu_20_04"
+	for _, f := range m {
+		f(d)
+	}
+	return d
+}
+
+func TestDeviceValidateCreate(t *testing.T) {
+	cases := map[string]struct {
+		d    *v1alpha2.Device
+		want field.ErrorList
+	}{
+		"Valid": {
+			d: device(withMetro("sv"), withUserDataRef("Secret")),
+		},
+		"AnyFacilityInMetro": {
+			d: device(withFacility("any"), withMetro("sv")),
+		},
+		"FacilityAndMetro": {
+			d: device(withFacility("sv15"), withMetro("sv")),
+			want: field.ErrorList{
+				field.Forbidden(forProvider.Child("metro"), "may not be specified together with a facility other than \"any\""),
+			},
+		},
+		"UserDataTemplate": {
+			d: device(withUserDataRef("ConfigMap"), withUserDataTemplate(&v1alpha2.UserDataTemplate{
+				Resources: []v1alpha2.TemplateResourceReference{{
+					Name:        "vlan",
+					ResourceRef: xpv1.TypedReference{APIVersion: "vlan.metal.equinix.com/v1alpha1", Kind: "VirtualNetwork", Name: "vlan"},
+				}},
+			})),
+		},
+		"UserDataTemplateWithoutRef": {
+			d: device(withUserDataTemplate(&v1alpha2.UserDataTemplate{
+				Secrets: []v1alpha2.TemplateSecretReference{
+					{Name: "creds", SecretRef: v1alpha2.NamespacedName{Namespace: "default", Name: "a"}},
+					{Name: "creds", SecretRef: v1alpha2.NamespacedName{Namespace: "default", Name: "b"}},
+				},
+			})),
+			want: field.ErrorList{
+				field.Forbidden(forProvider.Child("userdataTemplate"), "may only be specified together with userdataRef or userdataSources"),
+				field.Duplicate(forProvider.Child("userdataTemplate", "secrets").Index(1).Child("name"), "creds"),
+			},
+		},
+		"UserDataSources": {
+			d: device(func(d *v1alpha2.Device) {
+				inline := "#cloud-config"
+				d.Spec.ForProvider.UserDataSources = []v1alpha2.UserDataSource{
+					{Inline: &inline},
+					{Ref: &v1alpha2.DataKeySelector{NamespacedName: v1alpha2.NamespacedName{Namespace: "default", Name: "script"}, Kind: "ConfigMap"}},
+					{},
+				}
+			}, withUserDataRef("Secret")),
+			want: field.ErrorList{
+				field.Forbidden(forProvider.Child("userdataSources"), "may not be specified together with userdata or userdataRef"),
+				field.Required(forProvider.Child("userdataSources").Index(2), "one of inline or ref"),
+			},
+		},
+		"TerminationTimeAndTTL": {
+			d: device(func(d *v1alpha2.Device) {
+				d.Spec.ForProvider.TerminationTime = &metav1.Time{}
+				d.Spec.ForProvider.TTL = &metav1.Duration{}
+			}),
+			want: field.ErrorList{
+				field.Forbidden(forProvider.Child("ttl"), "may not be specified together with terminationTime"),
+				field.Invalid(forProvider.Child("ttl"), "0s", "must be positive"),
+			},
+		},
+		"Storage": {
+			d: device(withStorage(&v1alpha2.Storage{
+				Disks: []v1alpha2.Disk{
+					{Device: "/dev/sda", Partitions: []v1alpha2.Partition{{Label: "BIOS", Number: 1, Size: "4096"}, {Label: "ROOT", Number: 2, Size: "0"}}},
+					{Device: "/dev/nvme0n1", Partitions: []v1alpha2.Partition{{Label: "ROOT", Number: 1, Size: "0"}}},
+				},
+				RAID: []v1alpha2.RAID{{Devices: []string{"/dev/sda2", "/dev/nvme0n1p1"}, Level: "1", Name: "/dev/md/ROOT"}},
+				Filesystems: []v1alpha2.Filesystem{
+					{Mount: v1alpha2.Mount{Device: "/dev/md/ROOT", Format: "ext4", Point: "/"}},
+				},
+			})),
+		},
+		"InvalidStorage": {
+			d: device(withStorage(&v1alpha2.Storage{
+				Disks: []v1alpha2.Disk{
+					{Device: "/dev/sda", Partitions: []v1alpha2.Partition{{Label: "ROOT", Number: 1, Size: "0"}, {Label: "DATA", Number: 2, Size: "4GB"}}},
+				},
+				Filesystems: []v1alpha2.Filesystem{
+					{Mount: v1alpha2.Mount{Device: "/dev/sdb1", Format: "ext4", Point: "/"}},
+					{Mount: v1alpha2.Mount{Device: "/dev/sda2", Format: "ext4", Point: "/"}},
+				},
+			})),
+			want: field.ErrorList{
+				field.Invalid(forProvider.Child("storage", "disks").Index(0).Child("partitions").Index(0).Child("size"), "0", "only the last partition of a disk may use the rest of the disk"),
+				field.NotFound(forProvider.Child("storage", "filesystems").Index(0).Child("mount", "device"), "/dev/sdb1"),
+				field.Duplicate(forProvider.Child("storage", "filesystems").Index(1).Child("mount", "point"), "/"),
+			},
+		},
+		"InvalidUserDataRefKind": {
+			d: device(withUserDataRef("Pod")),
+			want: field.ErrorList{
+				field.NotSupported(forProvider.Child("userdataRef", "kind"), "Pod", userDataRefKinds),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := deviceValidator{}.validateCreate(tc.d)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("validateCreate(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDeviceValidateUpdate(t *testing.T) {
+	cases := map[string]struct {
+		old  *v1alpha2.Device
+		d    *v1alpha2.Device
+		want field.ErrorList
+	}{
+		"NotCreated": {
+			old: device(withPlan("c3.small.x86")),
+			d:   device(withPlan("m3.large.x86")),
+		},
+		"NotCreatedWithExternalNameDefaulted": {
+			old: device(withExternalName("device"), withFacility("sv15")),
+			d:   device(withExternalName("device"), withFacility("da11"), withMetro("sv")),
+			want: field.ErrorList{
+				field.Forbidden(forProvider.Child("metro"), "may not be specified together with a facility other than \"any\""),
+			},
+		},
+		"CreatedBeforeStatusPersisted": {
+			old: device(withExternalName("id")),
+			d:   device(withExternalName("id"), withPlan("m3.large.x86")),
+			want: field.ErrorList{
+				field.Invalid(forProvider.Child("plan"), "m3.large.x86", msgImmutable),
+			},
+		},
+		"PlanChanged": {
+			old: device(withObserved("sv15", "sv")),
+			d:   device(withObserved("sv15", "sv"), withPlan("m3.large.x86")),
+			want: field.ErrorList{
+				field.Invalid(forProvider.Child("plan"), "m3.large.x86", msgImmutable),
+			},
+		},
+		"MetroLateInitialized": {
+			old: device(withFacility("sv15"), withObserved("sv15", "sv")),
+			d:   device(withFacility("sv15"), withMetro("sv"), withObserved("sv15", "sv")),
+		},
+		"MetroLateInitializedBeforeStatusPersisted": {
+			old: device(withFacility("sv15"), withExternalName("id")),
+			d:   device(withFacility("sv15"), withMetro("sv"), withExternalName("id")),
+		},
+		"FacilityMigratedToMetro": {
+			old: device(withFacility("sv15"), withMetro("sv"), withObserved("sv15", "sv")),
+			d:   device(withMetro("sv"), withObserved("sv15", "sv")),
+		},
+		"FacilityMigratedToOtherMetro": {
+			old: device(withFacility("sv15"), withObserved("sv15", "sv")),
+			d:   device(withMetro("da"), withObserved("sv15", "sv")),
+			want: field.ErrorList{
+				field.Invalid(forProvider.Child("metro"), "da", "must be the observed metro sv"),
+			},
+		},
+		"FacilityChanged": {
+			old: device(withFacility("sv15"), withObserved("sv15", "sv")),
+			d:   device(withFacility("da11"), withObserved("sv15", "sv")),
+			want: field.ErrorList{
+				field.Invalid(forProvider.Child("facility"), "da11", msgImmutable+"; it may only be removed in favour of the metro it is in"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := deviceValidator{}.validateUpdate(tc.old, tc.d)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("validateUpdate(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDeviceValidateLateInitialized(t *testing.T) {
+	observed := &packngo.Device{
+		Hostname: "device",
+		UserData: "Content-Type: multipart/mixed",
+		Facility: &packngo.Facility{Code: "sv15"},
+		Metro:    &packngo.Metro{Code: "sv"},
+	}
+
+	cases := map[string]struct {
+		d *v1alpha2.Device
+	}{
+		"UserDataRef": {
+			d: device(withFacility("sv15"), withUserDataRef("Secret"), withObserved("sv15", "sv")),
+		},
+		"UserDataSources": {
+			d: device(withFacility("sv15"), withObserved("sv15", "sv"), func(d *v1alpha2.Device) {
+				inline := "#cloud-config"
+				d.Spec.ForProvider.UserDataSources = []v1alpha2.UserDataSource{{Inline: &inline}}
+			}),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d := tc.d.DeepCopy()
+			devicesclient.LateInitialize(&d.Spec.ForProvider, observed)
+			got := deviceValidator{}.validateUpdate(tc.d, d)
+			if diff := cmp.Diff(field.ErrorList(nil), got); diff != "" {
+				t.Errorf("validateUpdate(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/packethost/crossplane-provider-equinix-metal/apis/vlan/v1alpha1"
+)
+
+type virtualNetworkValidator struct{}
+
+func (virtualNetworkValidator) new() client.Object { return &v1alpha1.VirtualNetwork{} }
+
+func (virtualNetworkValidator) validateCreate(obj client.Object) field.ErrorList {
+	fp := obj.(*v1alpha1.VirtualNetwork).Spec.ForProvider
+	return validateLocation(forProvider, fp.Facility, fp.Metro)
+}
+
+func (virtualNetworkValidator) validateUpdate(oldObj, obj client.Object) field.ErrorList {
+	old, vn := oldObj.(*v1alpha1.VirtualNetwork), obj.(*v1alpha1.VirtualNetwork)
+	o, n := old.Spec.ForProvider, vn.Spec.ForProvider
+
+	if !created(old) {
+		if metroLateInitialized(o.Facility, o.Metro, n.Facility, n.Metro) {
+			return nil
+		}
+		return validateLocation(forProvider, n.Facility, n.Metro)
+	}
+
+	errs := immutableOnceSet(forProvider.Child("projectID"), o.ProjectID, n.ProjectID, o.ProjectID == "")
+	errs = append(errs, immutableOnceSet(forProvider.Child("vxlan"), o.VXLAN, n.VXLAN, o.VXLAN == 0)...)
+	errs = append(errs, validateLocationUpdate(forProvider, o.Facility, o.Metro, n.Facility, n.Metro,
+		vn.Status.AtProvider.FacilityCode, vn.Status.AtProvider.Metro)...)
+	return errs
+}
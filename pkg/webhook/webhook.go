@@ -0,0 +1,191 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhook contains the validating admission webhooks of the Equinix
+// Metal managed resources. They reject specs the controllers could otherwise
+// only report, or silently ignore, at reconcile time.
+package webhook
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	admissionv1 "k8s.io/api/admission/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/packethost/crossplane-provider-equinix-metal/pkg/clients"
+)
+
+// Paths at which the validating webhooks are served. They must match the
+// ValidatingWebhookConfiguration of the provider package.
+const (
+	PathDevice         = "/validate-server-metal-equinix-com-v1alpha2-device"
+	PathVirtualNetwork = "/validate-vlan-metal-equinix-com-v1alpha1-virtualnetwork"
+	PathAssignment     = "/validate-ports-metal-equinix-com-v1alpha1-assignment"
+)
+
+const (
+	errNewDecoder = "cannot create admission decoder"
+
+	msgImmutable = "field is immutable"
+)
+
+// Setup registers the validating webhooks of Devices, VirtualNetworks and
+// Assignments with the webhook server of the supplied manager.
+func Setup(mgr ctrl.Manager) error {
+	d, err := admission.NewDecoder(mgr.GetScheme())
+	if err != nil {
+		return errors.Wrap(err, errNewDecoder)
+	}
+
+	srv := mgr.GetWebhookServer()
+	for path, v := range map[string]validator{
+		PathDevice:         deviceValidator{},
+		PathVirtualNetwork: virtualNetworkValidator{},
+		PathAssignment:     assignmentValidator{},
+	} {
+		srv.Register(path, &crwebhook.Admission{Handler: &handler{decoder: d, validator: v}})
+	}
+	return nil
+}
+
+// A validator validates the creation and update of one kind of resource.
+type validator interface {
+	// new returns an empty object of the validated kind.
+	new() client.Object
+	validateCreate(obj client.Object) field.ErrorList
+	validateUpdate(old, obj client.Object) field.ErrorList
+}
+
+// A handler admits the resources its validator finds no errors in.
+type handler struct {
+	decoder   *admission.Decoder
+	validator validator
+}
+
+// Handle an admission request.
+func (h *handler) Handle(_ context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+
+	obj := h.validator.new()
+	if err := h.decoder.Decode(req, obj); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	var errs field.ErrorList
+	switch req.Operation {
+	case admissionv1.Create:
+		errs = h.validator.validateCreate(obj)
+	case admissionv1.Update:
+		// Resources being deleted only have their finalizers removed.
+		if meta.WasDeleted(obj) {
+			return admission.Allowed("")
+		}
+		old := h.validator.new()
+		if err := h.decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		errs = h.validator.validateUpdate(old, obj)
+	}
+	if len(errs) == 0 {
+		return admission.Allowed("")
+	}
+
+	gk := obj.GetObjectKind().GroupVersionKind().GroupKind()
+	return admission.Denied(kerrors.NewInvalid(gk, req.Name, errs).Error())
+}
+
+// immutable returns an error if the supplied value differs from its old value.
+func immutable(p *field.Path, old, v interface{}) field.ErrorList {
+	if cmp.Equal(old, v) {
+		return nil
+	}
+	return field.ErrorList{field.Invalid(p, v, msgImmutable)}
+}
+
+// immutableOnceSet returns an error if the supplied value differs from its old
+// value, unless the old value was unset. Such fields may be resolved from a
+// reference or late-initialized after the resource was created.
+func immutableOnceSet(p *field.Path, old, v interface{}, unset bool) field.ErrorList {
+	if unset {
+		return nil
+	}
+	return immutable(p, old, v)
+}
+
+// created returns true if the external resource of the supplied managed
+// resource was created. Its external name is set to the ID of the external
+// resource once it is created, and to the name of the managed resource until
+// then. Its status may not record the ID yet, as it is persisted separately.
+func created(o client.Object) bool {
+	en := meta.GetExternalName(o)
+	return en != "" && en != o.GetName()
+}
+
+// metroLateInitialized returns true if the only change to a location is the
+// metro being set alongside the facility, as the controllers late-initialize
+// it. The metro is then the one the facility is in, rather than a conflicting
+// location.
+func metroLateInitialized(oldFacility, oldMetro, facility, metro string) bool {
+	return oldFacility != "" && oldMetro == "" && facility == oldFacility && metro != ""
+}
+
+// validateLocation returns an error if both a facility and a metro are
+// specified. A metro may only accompany the "any" facility.
+func validateLocation(p *field.Path, facility, metro string) field.ErrorList {
+	if facility == "" || metro == "" || facility == clients.FacilityAny {
+		return nil
+	}
+	return field.ErrorList{field.Forbidden(p.Child("metro"), "may not be specified together with a facility other than \""+clients.FacilityAny+"\"")}
+}
+
+// validateLocationUpdate returns an error if the location of a created
+// resource is changed. The controllers late-initialize the metro of resources
+// created in a facility, after which the facility may be removed. No other
+// change is allowed.
+func validateLocationUpdate(p *field.Path, oldFacility, oldMetro, facility, metro, observedFacility, observedMetro string) field.ErrorList {
+	if facility == oldFacility && metro == oldMetro {
+		return nil
+	}
+
+	var errs field.ErrorList
+	if facility != oldFacility && facility != "" {
+		errs = append(errs, field.Invalid(p.Child("facility"), facility, msgImmutable+"; it may only be removed in favour of the metro it is in"))
+	}
+	if metro != oldMetro && oldMetro != "" {
+		errs = append(errs, field.Invalid(p.Child("metro"), metro, msgImmutable))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	if facility == "" && metro == "" {
+		return field.ErrorList{field.Required(p.Child("metro"), "the metro of the observed location is required when the facility is removed")}
+	}
+	if !clients.LocationCompatible(facility, metro, observedFacility, observedMetro) {
+		return field.ErrorList{field.Invalid(p.Child("metro"), metro, "must be the observed metro "+observedMetro)}
+	}
+	return nil
+}